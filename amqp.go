@@ -0,0 +1,46 @@
+package amqpx
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Types of the underlying amqp driver, so your code doesn't have to import it.
+type (
+	// Table stores user supplied fields, such as message headers or queue arguments.
+	Table = amqp.Table
+
+	// Publishing captures the client message sent to the broker.
+	Publishing = amqp.Publishing
+
+	// Delivery captures the fields of a message delivered by the broker.
+	Delivery = amqp.Delivery
+
+	// Acknowledger acknowledges or rejects a delivery.
+	Acknowledger = amqp.Acknowledger
+
+	// Queue captures the current server state of a queue.
+	Queue = amqp.Queue
+
+	// Confirmation notifies the acknowledgment or negative acknowledgement of a publishing.
+	Confirmation = amqp.Confirmation
+
+	// Return captures a message returned by the broker, because it could not be routed.
+	Return = amqp.Return
+
+	// AMQPError is an error replied by the broker, or raised by the driver.
+	AMQPError = amqp.Error
+)
+
+// Delivery modes of a publishing.
+const (
+	Transient  = amqp.Transient
+	Persistent = amqp.Persistent
+)
+
+// Exchange types.
+const (
+	ExchangeDirect  = amqp.ExchangeDirect
+	ExchangeFanout  = amqp.ExchangeFanout
+	ExchangeTopic   = amqp.ExchangeTopic
+	ExchangeHeaders = amqp.ExchangeHeaders
+)
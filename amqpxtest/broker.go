@@ -0,0 +1,374 @@
+package amqpxtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// Broker is an in-memory AMQP broker with a single virtual host.
+// It routes messages published by its clients through direct, fanout, topic and headers exchanges,
+// and dispatches them to the consumers of its queues.
+type Broker struct {
+	mutex     sync.Mutex
+	exchanges map[string]*exchange
+	queues    map[string]*queue
+	outbox    []func()
+}
+
+// NewBroker returns a new Broker instance, with the default and the "amq.*" exchanges.
+func NewBroker() *Broker {
+	broker := &Broker{
+		exchanges: map[string]*exchange{},
+		queues:    map[string]*queue{},
+	}
+
+	for name, kind := range map[string]string{
+		"":            amqpx.ExchangeDirect,
+		"amq.direct":  amqpx.ExchangeDirect,
+		"amq.fanout":  amqpx.ExchangeFanout,
+		"amq.topic":   amqpx.ExchangeTopic,
+		"amq.headers": amqpx.ExchangeHeaders,
+		"amq.match":   amqpx.ExchangeHeaders,
+	} {
+		broker.exchanges[name] = &exchange{name: name, kind: kind, durable: true}
+	}
+
+	return broker
+}
+
+// Exchange returns the type of the exchange with given name, if it exists.
+func (e *Broker) Exchange(name string) (string, bool) {
+	e.lock()
+	defer e.unlock()
+
+	exchange, ok := e.exchanges[name]
+	if !ok {
+		return "", false
+	}
+
+	return exchange.kind, true
+}
+
+// Queue returns the state of the queue with given name, if it exists.
+func (e *Broker) Queue(name string) (amqpx.Queue, bool) {
+	e.lock()
+	defer e.unlock()
+
+	queue, ok := e.queues[name]
+	if !ok {
+		return amqpx.Queue{}, false
+	}
+
+	return queue.state(), true
+}
+
+// Messages returns the messages of the queue with given name which are waiting to be delivered.
+// Returned deliveries have no acknowledger: they're left untouched in the queue.
+func (e *Broker) Messages(name string) []amqpx.Delivery {
+	e.lock()
+	defer e.unlock()
+
+	queue, ok := e.queues[name]
+	if !ok {
+		return nil
+	}
+
+	deliveries := make([]amqpx.Delivery, 0, len(queue.messages))
+	for _, message := range queue.messages {
+		deliveries = append(deliveries, message.delivery(nil, 0, ""))
+	}
+
+	return deliveries
+}
+
+// lock acquires the broker's lock.
+func (e *Broker) lock() {
+	e.mutex.Lock()
+}
+
+// unlock releases the broker's lock, then runs notifications queued while it was held.
+func (e *Broker) unlock() {
+	outbox := e.outbox
+	e.outbox = nil
+	e.mutex.Unlock()
+
+	for _, notify := range outbox {
+		notify()
+	}
+}
+
+// notify queues given notification until the broker's lock is released.
+// It avoids to block the broker when a listener is slow, or calls it back.
+func (e *Broker) notify(notification func()) {
+	e.outbox = append(e.outbox, notification)
+}
+
+// declareExchange creates an exchange unless an equivalent one already exists.
+func (e *Broker) declareExchange(name, kind string, durable, autoDelete, internal bool) error {
+	if name == "" {
+		return errDefaultExchange()
+	}
+
+	current, ok := e.exchanges[name]
+	if ok {
+		if current.kind != kind {
+			return errInequivalentArgument("type", "exchange", name, kind, current.kind)
+		}
+		if current.durable != durable {
+			return errInequivalentArgument("durable", "exchange", name, durable, current.durable)
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(name, "amq.") {
+		return errReservedExchange(name)
+	}
+
+	switch kind {
+	case amqpx.ExchangeDirect, amqpx.ExchangeFanout, amqpx.ExchangeTopic, amqpx.ExchangeHeaders:
+	default:
+		return errInvalidExchangeType(kind)
+	}
+
+	e.exchanges[name] = &exchange{
+		name:       name,
+		kind:       kind,
+		durable:    durable,
+		autoDelete: autoDelete,
+		internal:   internal,
+	}
+
+	return nil
+}
+
+// lookupExchange returns the exchange with given name.
+func (e *Broker) lookupExchange(name string) (*exchange, error) {
+	exchange, ok := e.exchanges[name]
+	if !ok {
+		return nil, errExchangeNotFound(name)
+	}
+
+	return exchange, nil
+}
+
+// deleteExchange removes an exchange and every binding from or to it.
+func (e *Broker) deleteExchange(name string, ifUnused bool) error {
+	if name == "" {
+		return errDefaultExchange()
+	}
+
+	current, ok := e.exchanges[name]
+	if !ok {
+		return nil
+	}
+	if ifUnused && len(current.bindings) > 0 {
+		return errInUse("exchange", name)
+	}
+
+	delete(e.exchanges, name)
+	e.unbind(func(_ *exchange, binding *binding) bool {
+		return !binding.queue && binding.destination == name
+	})
+
+	return nil
+}
+
+// bind adds a binding from given source exchange, unless it already exists.
+func (e *Broker) bind(source string, binding *binding) error {
+	if source == "" || (!binding.queue && binding.destination == "") {
+		return errDefaultExchange()
+	}
+
+	exchange, err := e.lookupExchange(source)
+	if err != nil {
+		return err
+	}
+
+	if !binding.queue {
+		_, err = e.lookupExchange(binding.destination)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, current := range exchange.bindings {
+		if current.equal(binding) {
+			return nil
+		}
+	}
+
+	exchange.bindings = append(exchange.bindings, binding)
+
+	return nil
+}
+
+// unbind removes every binding matching given predicate.
+// Auto-delete exchanges are removed once they have no binding left.
+func (e *Broker) unbind(predicate func(source *exchange, binding *binding) bool) {
+	for name, exchange := range e.exchanges {
+		count := len(exchange.bindings)
+		bindings := exchange.bindings[:0]
+		for _, binding := range exchange.bindings {
+			if !predicate(exchange, binding) {
+				bindings = append(bindings, binding)
+			}
+		}
+		exchange.bindings = bindings
+
+		if exchange.autoDelete && count > 0 && len(bindings) == 0 {
+			delete(e.exchanges, name)
+		}
+	}
+}
+
+// declareQueue creates a queue unless an equivalent one already exists.
+// An empty name creates a queue with a name generated by the broker.
+func (e *Broker) declareQueue(owner *Client, name string, durable, autoDelete, exclusive bool) (*queue, error) {
+	if name == "" {
+		name = generateName("amq.gen-")
+	}
+
+	current, ok := e.queues[name]
+	if ok {
+		if current.owner != nil && current.owner != owner {
+			return nil, errLockedQueue(name)
+		}
+		if current.durable != durable {
+			return nil, errInequivalentArgument("durable", "queue", name, durable, current.durable)
+		}
+		if current.autoDelete != autoDelete {
+			return nil, errInequivalentArgument("auto_delete", "queue", name, autoDelete, current.autoDelete)
+		}
+		return current, nil
+	}
+
+	queue := &queue{
+		name:       name,
+		durable:    durable,
+		autoDelete: autoDelete,
+	}
+	if exclusive {
+		queue.owner = owner
+	}
+
+	e.queues[name] = queue
+
+	return queue, nil
+}
+
+// lookupQueue returns the queue with given name, if it's accessible by given client.
+func (e *Broker) lookupQueue(client *Client, name string) (*queue, error) {
+	queue, ok := e.queues[name]
+	if !ok {
+		return nil, errQueueNotFound(name)
+	}
+	if queue.owner != nil && queue.owner != client {
+		return nil, errLockedQueue(name)
+	}
+
+	return queue, nil
+}
+
+// deleteQueue removes a queue and its bindings, and cancels its consumers.
+func (e *Broker) deleteQueue(queue *queue) int {
+	count := len(queue.messages)
+
+	queue.deleted = true
+	queue.messages = nil
+	delete(e.queues, queue.name)
+
+	e.unbind(func(_ *exchange, binding *binding) bool {
+		return binding.queue && binding.destination == queue.name
+	})
+
+	for _, consumer := range queue.consumers {
+		consumer.channel.removeConsumer(consumer, true)
+	}
+
+	return count
+}
+
+// publish routes a message from given exchange to every matching queue.
+// It returns whether the message was routed to at least one queue.
+func (e *Broker) publish(exchange *exchange, key string, publishing amqp.Publishing) bool {
+	queues := map[string]*queue{}
+	e.route(exchange, key, publishing.Headers, map[string]bool{}, queues)
+
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		queue := queues[name]
+		queue.messages = append(queue.messages, newMessage(exchange.name, key, publishing))
+		queue.dispatch()
+	}
+
+	return len(queues) > 0
+}
+
+// route collects the queues matched by given routing key and headers, following exchange to exchange bindings.
+func (e *Broker) route(exchange *exchange, key string, headers amqp.Table,
+	visited map[string]bool, queues map[string]*queue) {
+
+	if visited[exchange.name] {
+		return
+	}
+	visited[exchange.name] = true
+
+	if exchange.name == "" {
+		queue, ok := e.queues[key]
+		if ok {
+			queues[key] = queue
+		}
+		return
+	}
+
+	for _, binding := range exchange.bindings {
+		if !binding.matches(exchange.kind, key, headers) {
+			continue
+		}
+
+		if binding.queue {
+			queue, ok := e.queues[binding.destination]
+			if ok {
+				queues[queue.name] = queue
+			}
+			continue
+		}
+
+		destination, ok := e.exchanges[binding.destination]
+		if ok {
+			e.route(destination, key, headers, visited, queues)
+		}
+	}
+}
+
+// release deletes the exclusive queues owned by given client.
+func (e *Broker) release(client *Client) {
+	for _, queue := range e.queues {
+		if queue.owner == client {
+			e.deleteQueue(queue)
+		}
+	}
+}
+
+// generateName returns a random name with given prefix.
+func generateName(prefix string) string {
+	buffer := make([]byte, 12)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		panic(err)
+	}
+
+	return prefix + hex.EncodeToString(buffer)
+}
@@ -0,0 +1,667 @@
+package amqpxtest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// Channel implements the amqpx.Channel interface on a Broker.
+// Like with RabbitMQ, a channel exception replied by the broker closes the channel.
+type Channel struct {
+	broker    *Broker
+	client    *Client
+	closed    bool
+	confirm   bool
+	published uint64
+	delivered uint64
+	prefetch  int
+	unacked   map[uint64]*pending
+	consumers map[string]*consumer
+
+	listeners struct {
+		sync.Mutex
+		closed  bool
+		closes  []chan *amqp.Error
+		confirm []chan amqp.Confirmation
+		returns []chan amqp.Return
+		cancels []chan string
+	}
+}
+
+// newChannel returns a new Channel for given client.
+func newChannel(broker *Broker, client *Client) *Channel {
+	return &Channel{
+		broker:    broker,
+		client:    client,
+		unacked:   map[uint64]*pending{},
+		consumers: map[string]*consumer{},
+	}
+}
+
+// Close closes the channel: unacknowledged messages are requeued, and its consumers are canceled.
+func (e *Channel) Close() error {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	if !e.closed {
+		e.shutdown(nil)
+	}
+
+	return nil
+}
+
+// IsClosed returns if the channel is closed.
+func (e *Channel) IsClosed() bool {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	return e.closed
+}
+
+// NotifyClose registers a listener for when the channel is closed.
+// The listener receives the channel exception, if any, then it's closed.
+func (e *Channel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		close(receiver)
+	} else {
+		e.listeners.closes = append(e.listeners.closes, receiver)
+	}
+
+	return receiver
+}
+
+// NotifyPublish registers a listener for publishing confirmations.
+func (e *Channel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		close(confirm)
+	} else {
+		e.listeners.confirm = append(e.listeners.confirm, confirm)
+	}
+
+	return confirm
+}
+
+// NotifyReturn registers a listener for mandatory messages which could not be routed.
+func (e *Channel) NotifyReturn(returns chan amqp.Return) chan amqp.Return {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		close(returns)
+	} else {
+		e.listeners.returns = append(e.listeners.returns, returns)
+	}
+
+	return returns
+}
+
+// NotifyCancel registers a listener for consumers canceled by the broker, when their queue is deleted.
+func (e *Channel) NotifyCancel(cancellations chan string) chan string {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		close(cancellations)
+	} else {
+		e.listeners.cancels = append(e.listeners.cancels, cancellations)
+	}
+
+	return cancellations
+}
+
+// Qos sets how many unacknowledged messages the next consumers of the channel can receive.
+// Prefetch size and global mode are ignored.
+func (e *Channel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return e.call(func() error {
+		e.prefetch = prefetchCount
+		return nil
+	})
+}
+
+// Confirm puts the channel into confirm mode: every publishing is acknowledged to NotifyPublish listeners.
+func (e *Channel) Confirm(noWait bool) error {
+	return e.call(func() error {
+		e.confirm = true
+		return nil
+	})
+}
+
+// ExchangeDeclare declares an exchange on the broker.
+func (e *Channel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool,
+	args amqp.Table) error {
+
+	return e.call(func() error {
+		return e.broker.declareExchange(name, kind, durable, autoDelete, internal)
+	})
+}
+
+// ExchangeDeclarePassive checks if an exchange exists on the broker.
+func (e *Channel) ExchangeDeclarePassive(name, kind string, durable, autoDelete, internal, noWait bool,
+	args amqp.Table) error {
+
+	return e.call(func() error {
+		_, err := e.broker.lookupExchange(name)
+		return err
+	})
+}
+
+// ExchangeDelete removes an exchange from the broker.
+func (e *Channel) ExchangeDelete(name string, ifUnused, noWait bool) error {
+	return e.call(func() error {
+		return e.broker.deleteExchange(name, ifUnused)
+	})
+}
+
+// ExchangeBind binds an exchange to another exchange.
+func (e *Channel) ExchangeBind(destination, key, source string, noWait bool, args amqp.Table) error {
+	return e.call(func() error {
+		return e.broker.bind(source, &binding{destination: destination, key: key, args: args})
+	})
+}
+
+// ExchangeUnbind unbinds an exchange from another exchange.
+func (e *Channel) ExchangeUnbind(destination, key, source string, noWait bool, args amqp.Table) error {
+	return e.call(func() error {
+		e.unbind(source, &binding{destination: destination, key: key, args: args})
+		return nil
+	})
+}
+
+// QueueDeclare declares a queue on the broker.
+func (e *Channel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool,
+	args amqp.Table) (amqp.Queue, error) {
+
+	state := amqp.Queue{}
+	err := e.call(func() error {
+		queue, err := e.broker.declareQueue(e.client, name, durable, autoDelete, exclusive)
+		if err != nil {
+			return err
+		}
+		state = queue.state()
+		return nil
+	})
+
+	return state, err
+}
+
+// QueueDeclarePassive checks if a queue exists on the broker.
+func (e *Channel) QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool,
+	args amqp.Table) (amqp.Queue, error) {
+
+	state := amqp.Queue{}
+	err := e.call(func() error {
+		queue, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		state = queue.state()
+		return nil
+	})
+
+	return state, err
+}
+
+// QueueBind binds a queue to an exchange.
+func (e *Channel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return e.call(func() error {
+		_, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		return e.broker.bind(exchange, &binding{destination: name, queue: true, key: key, args: args})
+	})
+}
+
+// QueueUnbind unbinds a queue from an exchange.
+func (e *Channel) QueueUnbind(name, key, exchange string, args amqp.Table) error {
+	return e.call(func() error {
+		_, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		if exchange == "" {
+			return errDefaultExchange()
+		}
+		e.unbind(exchange, &binding{destination: name, queue: true, key: key, args: args})
+		return nil
+	})
+}
+
+// QueuePurge removes all messages from a queue which are not waiting for an acknowledgement.
+func (e *Channel) QueuePurge(name string, noWait bool) (int, error) {
+	count := 0
+	err := e.call(func() error {
+		queue, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		count = len(queue.messages)
+		queue.messages = nil
+		return nil
+	})
+
+	return count, err
+}
+
+// QueueDelete removes a queue from the broker, and cancels its consumers.
+func (e *Channel) QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error) {
+	count := 0
+	err := e.call(func() error {
+		if _, ok := e.broker.queues[name]; !ok {
+			return nil
+		}
+		queue, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		if ifUnused && len(queue.consumers) > 0 {
+			return errInUse("queue", name)
+		}
+		if ifEmpty && len(queue.messages) > 0 {
+			return errNotEmpty(name)
+		}
+		count = e.broker.deleteQueue(queue)
+		return nil
+	})
+
+	return count, err
+}
+
+// Consume starts delivering messages from a queue.
+// An empty consumer tag is replaced by a unique one, and the noLocal flag is ignored.
+func (e *Channel) Consume(name, tag string, autoAck, exclusive, noLocal, noWait bool,
+	args amqp.Table) (<-chan amqp.Delivery, error) {
+
+	var deliveries chan amqp.Delivery
+	err := e.call(func() error {
+		queue, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		if tag == "" {
+			tag = generateName("ctag-")
+		}
+		if _, ok := e.consumers[tag]; ok {
+			return errConsumerTagReused(tag)
+		}
+		if len(queue.consumers) > 0 && (exclusive || queue.consumers[0].exclusive) {
+			return errExclusiveUse(name)
+		}
+
+		consumer := &consumer{
+			tag:        tag,
+			channel:    e,
+			queue:      queue,
+			autoAck:    autoAck,
+			exclusive:  exclusive,
+			prefetch:   e.prefetch,
+			signal:     make(chan struct{}, 1),
+			done:       make(chan struct{}),
+			deliveries: make(chan amqp.Delivery),
+		}
+
+		e.consumers[tag] = consumer
+		queue.consumers = append(queue.consumers, consumer)
+		deliveries = consumer.deliveries
+
+		go consumer.run(e.broker)
+		queue.dispatch()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Cancel stops deliveries to a consumer, and closes its deliveries channel.
+func (e *Channel) Cancel(tag string, noWait bool) error {
+	return e.call(func() error {
+		consumer, ok := e.consumers[tag]
+		if ok {
+			e.removeConsumer(consumer, false)
+		}
+		return nil
+	})
+}
+
+// Get synchronously receives a single message from a queue.
+func (e *Channel) Get(name string, autoAck bool) (amqp.Delivery, bool, error) {
+	delivery := amqp.Delivery{}
+	ok := false
+	err := e.call(func() error {
+		queue, err := e.broker.lookupQueue(e.client, name)
+		if err != nil {
+			return err
+		}
+		if len(queue.messages) == 0 {
+			return nil
+		}
+
+		message := queue.shift()
+		tag := e.nextDeliveryTag()
+		delivery = message.delivery(e, tag, "")
+		delivery.MessageCount = uint32(len(queue.messages))
+		ok = true
+
+		if !autoAck {
+			e.unacked[tag] = &pending{tag: tag, queue: queue, message: message, delivery: delivery}
+		}
+		return nil
+	})
+
+	return delivery, ok, err
+}
+
+// Publish sends a message to an exchange.
+// The immediate flag is ignored.
+func (e *Channel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	return e.PublishWithContext(context.Background(), exchange, key, mandatory, immediate, msg)
+}
+
+// PublishWithContext sends a message to an exchange, unless given context is done.
+// The immediate flag is ignored.
+func (e *Channel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool,
+	msg amqp.Publishing) error {
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	return e.call(func() error {
+		source, err := e.broker.lookupExchange(exchange)
+		if err != nil {
+			return err
+		}
+		if source.internal {
+			return errInternalExchange(exchange)
+		}
+
+		routed := e.broker.publish(source, key, msg)
+		if !routed && mandatory {
+			e.broker.notify(func() {
+				e.notifyReturn(newReturn(exchange, key, msg))
+			})
+		}
+
+		if e.confirm {
+			e.published++
+			confirmation := amqp.Confirmation{DeliveryTag: e.published, Ack: true}
+			e.broker.notify(func() {
+				e.notifyPublish(confirmation)
+			})
+		}
+
+		return nil
+	})
+}
+
+// Ack acknowledges a delivery, or every delivery up to given tag if multiple is true.
+func (e *Channel) Ack(tag uint64, multiple bool) error {
+	return e.call(func() error {
+		return e.settle(tag, multiple, false)
+	})
+}
+
+// Nack negatively acknowledges a delivery, or every delivery up to given tag if multiple is true.
+func (e *Channel) Nack(tag uint64, multiple bool, requeue bool) error {
+	return e.call(func() error {
+		return e.settle(tag, multiple, requeue)
+	})
+}
+
+// Reject negatively acknowledges a delivery.
+func (e *Channel) Reject(tag uint64, requeue bool) error {
+	return e.Nack(tag, false, requeue)
+}
+
+// call runs given operation with the broker's lock held, unless the channel is closed.
+// If the operation fails with a channel exception, the channel is closed.
+func (e *Channel) call(operation func() error) error {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	if e.closed {
+		return amqp.ErrClosed
+	}
+
+	err := operation()
+	if err == nil {
+		return nil
+	}
+
+	exception := &amqp.Error{}
+	if errors.As(err, &exception) {
+		e.shutdown(exception)
+	}
+
+	return err
+}
+
+// unbind removes given binding from given source exchange.
+func (e *Channel) unbind(source string, target *binding) {
+	e.broker.unbind(func(exchange *exchange, binding *binding) bool {
+		return exchange.name == source && binding.equal(target)
+	})
+}
+
+// nextDeliveryTag returns the delivery tag of the next message delivered on the channel.
+func (e *Channel) nextDeliveryTag() uint64 {
+	e.delivered++
+	return e.delivered
+}
+
+// settle acknowledges or rejects unacknowledged deliveries.
+func (e *Channel) settle(tag uint64, multiple bool, requeue bool) error {
+	deliveries := []*pending{}
+
+	if multiple {
+		for current, delivery := range e.unacked {
+			if tag == 0 || current <= tag {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+	} else if delivery, ok := e.unacked[tag]; ok {
+		deliveries = append(deliveries, delivery)
+	}
+
+	if len(deliveries) == 0 && (tag != 0 || !multiple) {
+		return errUnknownDeliveryTag(tag)
+	}
+
+	e.release(deliveries, requeue)
+
+	return nil
+}
+
+// forget stops tracking given delivery, and returns if it was waiting for an acknowledgement.
+func (e *Channel) forget(delivery *pending) bool {
+	_, ok := e.unacked[delivery.tag]
+	if !ok {
+		return false
+	}
+
+	delete(e.unacked, delivery.tag)
+	if delivery.consumer != nil {
+		delivery.consumer.unacked--
+	}
+
+	return true
+}
+
+// release stops tracking given deliveries, puts them back in their queue if requeue is true,
+// and dispatches messages to consumers which can receive more of them.
+func (e *Channel) release(deliveries []*pending, requeue bool) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].tag < deliveries[j].tag
+	})
+
+	queues := []*queue{}
+	requeued := map[*queue][]*message{}
+
+	for _, delivery := range deliveries {
+		e.forget(delivery)
+		if _, ok := requeued[delivery.queue]; !ok {
+			queues = append(queues, delivery.queue)
+			requeued[delivery.queue] = []*message{}
+		}
+		if requeue {
+			requeued[delivery.queue] = append(requeued[delivery.queue], delivery.message)
+		}
+	}
+
+	for _, queue := range queues {
+		queue.requeue(requeued[queue]...)
+		queue.dispatch()
+	}
+}
+
+// removeConsumer cancels given consumer, and notifies listeners if it's canceled by the broker.
+// An auto-delete queue is removed with its last consumer.
+func (e *Channel) removeConsumer(consumer *consumer, notify bool) {
+	delete(e.consumers, consumer.tag)
+	consumer.queue.removeConsumer(consumer)
+	consumer.cancel()
+
+	if notify {
+		e.broker.notify(func() {
+			e.notifyCancel(consumer.tag)
+		})
+	}
+
+	queue := consumer.queue
+	if queue.autoDelete && !queue.deleted && len(queue.consumers) == 0 {
+		e.broker.deleteQueue(queue)
+	}
+}
+
+// shutdown closes the channel with given exception, if any.
+// Its consumers are canceled, unacknowledged messages are requeued and listeners are notified.
+func (e *Channel) shutdown(exception *amqp.Error) {
+	e.closed = true
+
+	for _, consumer := range e.consumers {
+		e.removeConsumer(consumer, false)
+	}
+
+	deliveries := make([]*pending, 0, len(e.unacked))
+	for _, delivery := range e.unacked {
+		deliveries = append(deliveries, delivery)
+	}
+	e.release(deliveries, true)
+
+	e.client.removeChannel(e)
+	e.broker.notify(func() {
+		e.notifyClose(exception)
+	})
+}
+
+// notifyClose sends given exception, if any, to close listeners, then closes every listener.
+func (e *Channel) notifyClose(exception *amqp.Error) {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		return
+	}
+	e.listeners.closed = true
+
+	for _, receiver := range e.listeners.closes {
+		if exception != nil {
+			receiver <- exception
+		}
+		close(receiver)
+	}
+	for _, receiver := range e.listeners.confirm {
+		close(receiver)
+	}
+	for _, receiver := range e.listeners.returns {
+		close(receiver)
+	}
+	for _, receiver := range e.listeners.cancels {
+		close(receiver)
+	}
+}
+
+// notifyPublish sends given confirmation to publish listeners.
+func (e *Channel) notifyPublish(confirmation amqp.Confirmation) {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		return
+	}
+
+	for _, receiver := range e.listeners.confirm {
+		receiver <- confirmation
+	}
+}
+
+// notifyReturn sends given returned message to return listeners.
+func (e *Channel) notifyReturn(message amqp.Return) {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		return
+	}
+
+	for _, receiver := range e.listeners.returns {
+		receiver <- message
+	}
+}
+
+// notifyCancel sends given consumer tag to cancel listeners.
+func (e *Channel) notifyCancel(tag string) {
+	e.listeners.Lock()
+	defer e.listeners.Unlock()
+
+	if e.listeners.closed {
+		return
+	}
+
+	for _, receiver := range e.listeners.cancels {
+		receiver <- tag
+	}
+}
+
+// newReturn returns the message returned by the broker for an unroutable publishing.
+func newReturn(exchange, key string, msg amqp.Publishing) amqp.Return {
+	return amqp.Return{
+		ReplyCode:       amqp.NoRoute,
+		ReplyText:       "NO_ROUTE",
+		Exchange:        exchange,
+		RoutingKey:      key,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		Headers:         msg.Headers,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	}
+}
+
+var _ amqpx.Channel = (*Channel)(nil)
@@ -0,0 +1,135 @@
+package amqpxtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// Client implements the amqpx.Client interface on a Broker.
+// It behaves like a single connection: its exclusive queues are deleted when it's closed.
+type Client struct {
+	broker   *Broker
+	channels map[*Channel]struct{}
+	stop     chan struct{}
+	stopping bool
+	closed   bool
+}
+
+// NewClient returns a new Client connected to given Broker.
+func NewClient(broker *Broker) *Client {
+	return &Client{
+		broker:   broker,
+		channels: map[*Channel]struct{}{},
+		stop:     make(chan struct{}),
+	}
+}
+
+// Broker returns the broker of the client.
+func (e *Client) Broker() *Broker {
+	return e.broker
+}
+
+// Channel returns a new Channel from current client unless it's closed.
+func (e *Client) Channel() (amqpx.Channel, error) {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	if e.closed || e.stopping {
+		return nil, errors.Wrap(amqpx.ErrClientClosed, amqpx.ErrMessageCannotOpenChannel)
+	}
+
+	channel := newChannel(e.broker, e)
+	e.channels[channel] = struct{}{}
+
+	return channel, nil
+}
+
+// Close closes the client and its channels.
+func (e *Client) Close() error {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	if e.closed {
+		return nil
+	}
+
+	e.halt()
+	e.closed = true
+	for channel := range e.channels {
+		channel.shutdown(nil)
+	}
+	e.broker.release(e)
+
+	return nil
+}
+
+// Shutdown stops handing out channels, waits for the open ones to be closed until given context is done,
+// then closes the client. Like the amqpx clients, it returns nil if the client is already closed.
+func (e *Client) Shutdown(ctx context.Context) error {
+	e.broker.lock()
+	if e.closed {
+		e.broker.unlock()
+		return nil
+	}
+	e.halt()
+	e.broker.unlock()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for e.openChannels() > 0 {
+		select {
+		case <-ctx.Done():
+			open := e.openChannels()
+			thr := e.Close()
+			_ = thr
+			return errors.Wrapf(ctx.Err(), "%d channels still open", open)
+		case <-ticker.C:
+		}
+	}
+
+	return e.Close()
+}
+
+// ShuttingDown implements amqpx.ShutdownNotifier interface.
+func (e *Client) ShuttingDown() <-chan struct{} {
+	return e.stop
+}
+
+// halt stops handing out channels, unless it's already done. It must be called with the broker lock held.
+func (e *Client) halt() {
+	if !e.stopping {
+		e.stopping = true
+		close(e.stop)
+	}
+}
+
+// openChannels returns the number of open channels of the client.
+func (e *Client) openChannels() int {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	return len(e.channels)
+}
+
+// IsClosed returns if the client is closed.
+func (e *Client) IsClosed() bool {
+	e.broker.lock()
+	defer e.broker.unlock()
+
+	return e.closed
+}
+
+// removeChannel forgets given closed channel.
+func (e *Client) removeChannel(channel *Channel) {
+	delete(e.channels, channel)
+}
+
+var (
+	_ amqpx.Client           = (*Client)(nil)
+	_ amqpx.ShutdownNotifier = (*Client)(nil)
+)
@@ -0,0 +1,393 @@
+package amqpxtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func TestClient_Routing(t *testing.T) {
+	is := require.New(t)
+
+	broker := amqpxtest.NewBroker()
+	client := amqpxtest.NewClient(broker)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	for _, name := range []string{"direct", "fanout", "topic", "headers"} {
+		_, err = channel.QueueDeclare(name, true, false, false, false, nil)
+		is.NoError(err)
+	}
+
+	is.NoError(channel.ExchangeDeclare("events", amqpx.ExchangeTopic, true, false, false, false, nil))
+	is.NoError(channel.QueueBind("direct", "orders", "amq.direct", false, nil))
+	is.NoError(channel.QueueBind("fanout", "", "amq.fanout", false, nil))
+	is.NoError(channel.QueueBind("topic", "orders.*.created", "events", false, nil))
+	is.NoError(channel.QueueBind("topic", "audit.#", "events", false, nil))
+	is.NoError(channel.QueueBind("headers", "", "amq.headers", false, amqp.Table{"x-match": "any", "tenant": "ulule"}))
+	is.NoError(channel.ExchangeBind("events", "#", "amq.fanout", false, nil))
+
+	publish := func(exchange, key string, headers amqp.Table) {
+		is.NoError(channel.Publish(exchange, key, false, false, amqp.Publishing{Headers: headers, Body: []byte(key)}))
+	}
+
+	publish("", "direct", nil)
+	publish("amq.direct", "orders", nil)
+	publish("amq.direct", "payments", nil)
+	publish("events", "orders.eu.created", nil)
+	publish("events", "orders.eu.updated", nil)
+	publish("events", "audit", nil)
+	publish("events", "audit.orders.eu", nil)
+	publish("amq.headers", "", amqp.Table{"tenant": "ulule"})
+	publish("amq.headers", "", amqp.Table{"tenant": "other"})
+	publish("amq.fanout", "audit.fanout", nil)
+
+	bodies := func(queue string) []string {
+		values := []string{}
+		for _, message := range broker.Messages(queue) {
+			values = append(values, string(message.Body))
+		}
+		return values
+	}
+
+	is.Equal([]string{"direct", "orders"}, bodies("direct"))
+	is.Equal([]string{"audit.fanout"}, bodies("fanout"))
+	is.Equal([]string{"orders.eu.created", "audit", "audit.orders.eu", "audit.fanout"}, bodies("topic"))
+	is.Equal([]string{""}, bodies("headers"))
+
+	state, ok := broker.Queue("topic")
+	is.True(ok)
+	is.Equal(4, state.Messages)
+
+	kind, ok := broker.Exchange("events")
+	is.True(ok)
+	is.Equal(amqpx.ExchangeTopic, kind)
+}
+
+func TestClient_Consume(t *testing.T) {
+	is := require.New(t)
+
+	client := amqpxtest.NewClient(amqpxtest.NewBroker())
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	queue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	is.NoError(err)
+	is.NotEmpty(queue.Name)
+
+	is.NoError(channel.Qos(1, 0, false))
+	deliveries, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	is.NoError(err)
+
+	for _, body := range []string{"first", "second"} {
+		is.NoError(channel.Publish("", queue.Name, false, false, amqp.Publishing{Body: []byte(body)}))
+	}
+
+	delivery := receive(t, deliveries)
+	is.Equal("first", string(delivery.Body))
+	is.False(delivery.Redelivered)
+	is.Equal(queue.Name, delivery.RoutingKey)
+	is.NotEmpty(delivery.ConsumerTag)
+
+	state, err := channel.QueueDeclarePassive(queue.Name, false, true, true, false, nil)
+	is.NoError(err)
+	is.Equal(1, state.Messages)
+	is.Equal(1, state.Consumers)
+
+	is.NoError(delivery.Nack(false, true))
+
+	delivery = receive(t, deliveries)
+	is.Equal("first", string(delivery.Body))
+	is.True(delivery.Redelivered)
+	is.NoError(delivery.Ack(false))
+
+	delivery = receive(t, deliveries)
+	is.Equal("second", string(delivery.Body))
+	is.NoError(delivery.Ack(false))
+
+	is.NoError(channel.Cancel(delivery.ConsumerTag, false))
+	_, ok := <-deliveries
+	is.False(ok)
+
+	_, err = channel.QueueDeclarePassive(queue.Name, false, true, true, false, nil)
+	is.Error(err)
+	is.True(channel.IsClosed())
+}
+
+func TestClient_Requeue(t *testing.T) {
+	is := require.New(t)
+
+	broker := amqpxtest.NewBroker()
+	client := amqpxtest.NewClient(broker)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	_, err = channel.QueueDeclare("requeue", true, false, false, false, nil)
+	is.NoError(err)
+
+	for _, body := range []string{"first", "second", "third"} {
+		is.NoError(channel.Publish("", "requeue", false, false, amqp.Publishing{Body: []byte(body)}))
+	}
+
+	delivery, ok, err := channel.Get("requeue", false)
+	is.NoError(err)
+	is.True(ok)
+	is.Equal("first", string(delivery.Body))
+	is.Equal(uint32(2), delivery.MessageCount)
+
+	delivery, ok, err = channel.Get("requeue", false)
+	is.NoError(err)
+	is.True(ok)
+	is.Equal("second", string(delivery.Body))
+
+	is.NoError(channel.Close())
+	is.NoError(channel.Close())
+
+	messages := broker.Messages("requeue")
+	is.Len(messages, 3)
+	is.Equal("first", string(messages[0].Body))
+	is.True(messages[0].Redelivered)
+	is.Equal("second", string(messages[1].Body))
+	is.Equal("third", string(messages[2].Body))
+	is.False(messages[2].Redelivered)
+
+	channel, err = client.Channel()
+	is.NoError(err)
+
+	count, err := channel.QueuePurge("requeue", false)
+	is.NoError(err)
+	is.Equal(3, count)
+
+	_, ok, err = channel.Get("requeue", true)
+	is.NoError(err)
+	is.False(ok)
+}
+
+func TestClient_Confirm(t *testing.T) {
+	is := require.New(t)
+
+	client := amqpxtest.NewClient(amqpxtest.NewBroker())
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	is.NoError(channel.Confirm(false))
+	confirms := channel.NotifyPublish(make(chan amqp.Confirmation, 2))
+	returns := channel.NotifyReturn(make(chan amqp.Return, 1))
+
+	is.NoError(channel.Publish("amq.direct", "unroutable", true, false, amqp.Publishing{Body: []byte("lost")}))
+	is.NoError(channel.Publish("amq.direct", "unroutable", false, false, amqp.Publishing{Body: []byte("lost")}))
+
+	returned := <-returns
+	is.Equal(uint16(amqp.NoRoute), returned.ReplyCode)
+	is.Equal("unroutable", returned.RoutingKey)
+	is.Equal("lost", string(returned.Body))
+
+	is.Equal(amqp.Confirmation{DeliveryTag: 1, Ack: true}, <-confirms)
+	is.Equal(amqp.Confirmation{DeliveryTag: 2, Ack: true}, <-confirms)
+
+	is.NoError(channel.Close())
+	_, ok := <-confirms
+	is.False(ok)
+	_, ok = <-returns
+	is.False(ok)
+}
+
+func TestClient_Exception(t *testing.T) {
+	is := require.New(t)
+
+	client := amqpxtest.NewClient(amqpxtest.NewBroker())
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	closes := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	err = channel.Publish("unknown", "", false, false, amqp.Publishing{})
+	is.Error(err)
+
+	exception := &amqp.Error{}
+	is.True(errors.As(err, &exception))
+	is.Equal(amqp.NotFound, exception.Code)
+	is.Equal("NOT_FOUND - no exchange 'unknown' in vhost '/'", exception.Reason)
+	is.Equal(exception, <-closes)
+
+	is.True(channel.IsClosed())
+	is.Equal(amqp.ErrClosed, channel.Publish("", "", false, false, amqp.Publishing{}))
+
+	channel, err = client.Channel()
+	is.NoError(err)
+
+	is.NoError(channel.ExchangeDeclare("events", amqpx.ExchangeTopic, true, false, false, false, nil))
+	err = channel.ExchangeDeclare("events", amqpx.ExchangeFanout, true, false, false, false, nil)
+	is.True(errors.As(err, &exception))
+	is.Equal(amqp.PreconditionFailed, exception.Code)
+}
+
+func TestClient_Close(t *testing.T) {
+	is := require.New(t)
+
+	broker := amqpxtest.NewBroker()
+	client := amqpxtest.NewClient(broker)
+	is.Equal(broker, client.Broker())
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	_, err = channel.QueueDeclare("exclusive", false, false, true, false, nil)
+	is.NoError(err)
+
+	other := amqpxtest.NewClient(broker)
+	otherChannel, err := other.Channel()
+	is.NoError(err)
+
+	_, err = otherChannel.QueueDeclare("exclusive", false, false, true, false, nil)
+	exception := &amqp.Error{}
+	is.True(errors.As(err, &exception))
+	is.Equal(amqp.ResourceLocked, exception.Code)
+
+	is.False(client.IsClosed())
+	is.NoError(client.Close())
+	is.NoError(client.Close())
+	is.True(client.IsClosed())
+	is.True(channel.IsClosed())
+
+	_, ok := broker.Queue("exclusive")
+	is.False(ok)
+
+	_, err = client.Channel()
+	is.Error(err)
+	is.True(errors.Is(err, amqpx.ErrClientClosed))
+	is.NoError(other.Close())
+}
+
+func TestClient_PublisherConsumer(t *testing.T) {
+	is := require.New(t)
+
+	type Event struct {
+		Message string
+	}
+
+	client := amqpxtest.NewClient(amqpxtest.NewBroker())
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	_, err = channel.QueueDeclare("events", true, false, false, false, nil)
+	is.NoError(err)
+	is.NoError(channel.QueueBind("events", "events.*", "amq.topic", false, nil))
+	is.NoError(channel.Close())
+
+	publisher, err := amqpx.NewPublisher[Event](client, "amq.topic", "events.created", amqpx.WithPublisherConfirm())
+	is.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan Event, 1)
+	consumer, err := amqpx.NewConsumer(client, "events",
+		func(ctx context.Context, event Event, delivery amqp.Delivery) error {
+			received <- event
+			return nil
+		},
+	)
+	is.NoError(err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- consumer.Consume(ctx)
+	}()
+
+	is.NoError(publisher.Publish(ctx, Event{Message: "hello"}))
+
+	select {
+	case event := <-received:
+		is.Equal("hello", event.Message)
+	case <-ctx.Done():
+		is.NoError(ctx.Err())
+	}
+
+	cancel()
+	is.NoError(<-done)
+}
+
+func receive(t *testing.T, deliveries <-chan amqp.Delivery) amqp.Delivery {
+	t.Helper()
+
+	select {
+	case delivery, ok := <-deliveries:
+		require.True(t, ok)
+		return delivery
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no delivery received")
+		return amqp.Delivery{}
+	}
+}
+
+func TestClient_Shutdown(t *testing.T) {
+	is := require.New(t)
+
+	client := amqpxtest.NewClient(amqpxtest.NewBroker())
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = client.Shutdown(ctx)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+	is.True(client.IsClosed())
+	is.True(channel.IsClosed())
+
+	client = amqpxtest.NewClient(amqpxtest.NewBroker())
+	channel, err = client.Channel()
+	is.NoError(err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Shutdown(context.Background())
+	}()
+
+	<-client.ShuttingDown()
+	_, err = client.Channel()
+	is.Equal(amqpx.ErrClientClosed, errors.Cause(err))
+	is.False(client.IsClosed())
+
+	is.NoError(channel.Close())
+	is.NoError(<-done)
+	is.True(client.IsClosed())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	is.NoError(client.Shutdown(ctx))
+	is.NoError(client.Close())
+	is.True(client.IsClosed())
+}
@@ -0,0 +1,5 @@
+// Package amqpxtest provides test utilities for amqpx.
+//
+// Its Broker is an in-memory AMQP 0-9-1 broker, and its Client a fake amqpx.Client connected to such a
+// Broker, so code depending on amqpx.Client can be tested hermetically, without a running RabbitMQ.
+package amqpxtest
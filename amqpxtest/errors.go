@@ -0,0 +1,106 @@
+package amqpxtest
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	// ErrServerAddressRequired occurs when given server address is empty.
+	ErrServerAddressRequired = fmt.Errorf("server address is required")
+
+	// ErrInvalidServerHeartbeat occurs when the defined server heartbeat is invalid.
+	ErrInvalidServerHeartbeat = fmt.Errorf("invalid server heartbeat")
+
+	// ErrInvalidServerChannelMax occurs when the defined server channel max is invalid.
+	ErrInvalidServerChannelMax = fmt.Errorf("invalid server channel max")
+
+	// ErrProxyAddressRequired occurs when given proxy address is empty.
+	ErrProxyAddressRequired = fmt.Errorf("proxy address is required")
+
+	// ErrProxyUpstreamRequired occurs when given proxy upstream address is empty.
+	ErrProxyUpstreamRequired = fmt.Errorf("proxy upstream address is required")
+
+	// ErrInvalidProxyURI occurs when given URI cannot be rewritten to target a proxy.
+	ErrInvalidProxyURI = fmt.Errorf("invalid proxy URI")
+
+	// ErrMalformedFrame occurs when the server receives a frame it cannot decode.
+	ErrMalformedFrame = fmt.Errorf("malformed frame")
+)
+
+// Error messages.
+const (
+	ErrMessageCannotStartServer = "cannot start server"
+	ErrMessageCannotStartProxy  = "cannot start proxy"
+)
+
+// vhost is the only virtual host served by a Broker.
+const vhost = "/"
+
+// newError returns a new channel exception replied by the broker.
+func newError(code int, text string, format string, args ...interface{}) *amqp.Error {
+	return &amqp.Error{
+		Code:   code,
+		Reason: fmt.Sprintf("%s - %s", text, fmt.Sprintf(format, args...)),
+		Server: true,
+	}
+}
+
+func errExchangeNotFound(name string) *amqp.Error {
+	return newError(amqp.NotFound, "NOT_FOUND", "no exchange '%s' in vhost '%s'", name, vhost)
+}
+
+func errQueueNotFound(name string) *amqp.Error {
+	return newError(amqp.NotFound, "NOT_FOUND", "no queue '%s' in vhost '%s'", name, vhost)
+}
+
+func errDefaultExchange() *amqp.Error {
+	return newError(amqp.AccessRefused, "ACCESS_REFUSED", "operation not permitted on the default exchange")
+}
+
+func errReservedExchange(name string) *amqp.Error {
+	return newError(amqp.AccessRefused, "ACCESS_REFUSED",
+		"exchange name '%s' contains reserved prefix 'amq.*'", name)
+}
+
+func errInternalExchange(name string) *amqp.Error {
+	return newError(amqp.AccessRefused, "ACCESS_REFUSED",
+		"cannot publish to internal exchange '%s' in vhost '%s'", name, vhost)
+}
+
+func errInvalidExchangeType(kind string) *amqp.Error {
+	return newError(amqp.CommandInvalid, "COMMAND_INVALID", "invalid exchange type '%s'", kind)
+}
+
+func errInequivalentArgument(argument, kind, name string, received, current interface{}) *amqp.Error {
+	return newError(amqp.PreconditionFailed, "PRECONDITION_FAILED",
+		"inequivalent arg '%s' for %s '%s' in vhost '%s': received '%v' but current is '%v'",
+		argument, kind, name, vhost, received, current)
+}
+
+func errInUse(kind, name string) *amqp.Error {
+	return newError(amqp.PreconditionFailed, "PRECONDITION_FAILED", "%s '%s' in vhost '%s' in use", kind, name, vhost)
+}
+
+func errNotEmpty(name string) *amqp.Error {
+	return newError(amqp.PreconditionFailed, "PRECONDITION_FAILED",
+		"queue '%s' in vhost '%s' is not empty", name, vhost)
+}
+
+func errUnknownDeliveryTag(tag uint64) *amqp.Error {
+	return newError(amqp.PreconditionFailed, "PRECONDITION_FAILED", "unknown delivery tag %d", tag)
+}
+
+func errExclusiveUse(name string) *amqp.Error {
+	return newError(amqp.AccessRefused, "ACCESS_REFUSED", "queue '%s' in vhost '%s' in exclusive use", name, vhost)
+}
+
+func errLockedQueue(name string) *amqp.Error {
+	return newError(amqp.ResourceLocked, "RESOURCE_LOCKED",
+		"cannot obtain exclusive access to locked queue '%s' in vhost '%s'", name, vhost)
+}
+
+func errConsumerTagReused(tag string) *amqp.Error {
+	return newError(amqp.NotAllowed, "NOT_ALLOWED", "attempt to reuse consumer tag '%s'", tag)
+}
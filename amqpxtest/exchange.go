@@ -0,0 +1,97 @@
+package amqpxtest
+
+import (
+	"reflect"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// exchange routes messages to queues or other exchanges using its bindings.
+type exchange struct {
+	name       string
+	kind       string
+	durable    bool
+	autoDelete bool
+	internal   bool
+	bindings   []*binding
+}
+
+// binding binds an exchange to a queue, or to another exchange.
+type binding struct {
+	destination string
+	queue       bool
+	key         string
+	args        amqp.Table
+}
+
+// equal returns if both bindings have the same destination, routing key and arguments.
+func (e *binding) equal(other *binding) bool {
+	return e.destination == other.destination && e.queue == other.queue &&
+		e.key == other.key && reflect.DeepEqual(e.args, other.args)
+}
+
+// matches returns if a message with given routing key and headers is routed by this binding,
+// for an exchange of given type.
+func (e *binding) matches(kind string, key string, headers amqp.Table) bool {
+	switch kind {
+	case amqpx.ExchangeFanout:
+		return true
+	case amqpx.ExchangeTopic:
+		return matchTopic(strings.Split(e.key, "."), strings.Split(key, "."))
+	case amqpx.ExchangeHeaders:
+		return matchHeaders(e.args, headers)
+	default:
+		return e.key == key
+	}
+}
+
+// matchTopic returns if given words match given pattern, where "*" substitutes exactly one word
+// and "#" zero or more words.
+func matchTopic(pattern []string, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchTopic(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchTopic(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && words[0] == pattern[0] && matchTopic(pattern[1:], words[1:])
+	}
+}
+
+// matchHeaders returns if given headers match the arguments of a binding.
+// Every argument must match unless "x-match" is "any", and arguments prefixed by "x-" are ignored.
+func matchHeaders(args amqp.Table, headers amqp.Table) bool {
+	all := args["x-match"] != "any"
+	matched := 0
+	expected := 0
+
+	for key, value := range args {
+		if strings.HasPrefix(key, "x-") {
+			continue
+		}
+
+		expected++
+		header, ok := headers[key]
+		if ok && (value == nil || reflect.DeepEqual(header, value)) {
+			matched++
+		}
+	}
+
+	if all {
+		return matched == expected
+	}
+
+	return matched > 0
+}
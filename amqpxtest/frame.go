@@ -0,0 +1,339 @@
+package amqpxtest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// protocolHeader is sent by clients to start an AMQP 0-9-1 connection.
+const protocolHeader = "AMQP\x00\x00\x09\x01"
+
+// Frame types.
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 206
+)
+
+// Methods identifiers, as class id << 16 | method id.
+const (
+	methodConnectionStart   = 10<<16 | 10
+	methodConnectionStartOk = 10<<16 | 11
+	methodConnectionTune    = 10<<16 | 30
+	methodConnectionTuneOk  = 10<<16 | 31
+	methodConnectionOpen    = 10<<16 | 40
+	methodConnectionOpenOk  = 10<<16 | 41
+	methodConnectionClose   = 10<<16 | 50
+	methodConnectionCloseOk = 10<<16 | 51
+	methodChannelOpen       = 20<<16 | 10
+	methodChannelOpenOk     = 20<<16 | 11
+	methodChannelFlow       = 20<<16 | 20
+	methodChannelFlowOk     = 20<<16 | 21
+	methodChannelClose      = 20<<16 | 40
+	methodChannelCloseOk    = 20<<16 | 41
+	methodExchangeDeclare   = 40<<16 | 10
+	methodExchangeDeclareOk = 40<<16 | 11
+	methodExchangeDelete    = 40<<16 | 20
+	methodExchangeDeleteOk  = 40<<16 | 21
+	methodExchangeBind      = 40<<16 | 30
+	methodExchangeBindOk    = 40<<16 | 31
+	methodExchangeUnbind    = 40<<16 | 40
+	methodExchangeUnbindOk  = 40<<16 | 51
+	methodQueueDeclare      = 50<<16 | 10
+	methodQueueDeclareOk    = 50<<16 | 11
+	methodQueueBind         = 50<<16 | 20
+	methodQueueBindOk       = 50<<16 | 21
+	methodQueuePurge        = 50<<16 | 30
+	methodQueuePurgeOk      = 50<<16 | 31
+	methodQueueDelete       = 50<<16 | 40
+	methodQueueDeleteOk     = 50<<16 | 41
+	methodQueueUnbind       = 50<<16 | 50
+	methodQueueUnbindOk     = 50<<16 | 51
+	methodBasicQos          = 60<<16 | 10
+	methodBasicQosOk        = 60<<16 | 11
+	methodBasicConsume      = 60<<16 | 20
+	methodBasicConsumeOk    = 60<<16 | 21
+	methodBasicCancel       = 60<<16 | 30
+	methodBasicCancelOk     = 60<<16 | 31
+	methodBasicPublish      = 60<<16 | 40
+	methodBasicReturn       = 60<<16 | 50
+	methodBasicDeliver      = 60<<16 | 60
+	methodBasicGet          = 60<<16 | 70
+	methodBasicGetOk        = 60<<16 | 71
+	methodBasicGetEmpty     = 60<<16 | 72
+	methodBasicAck          = 60<<16 | 80
+	methodBasicReject       = 60<<16 | 90
+	methodBasicNack         = 60<<16 | 120
+	methodConfirmSelect     = 85<<16 | 10
+	methodConfirmSelectOk   = 85<<16 | 11
+)
+
+// classBasic is the class of content frames.
+const classBasic = 60
+
+// Content properties flags.
+const (
+	flagContentType     = 0x8000
+	flagContentEncoding = 0x4000
+	flagHeaders         = 0x2000
+	flagDeliveryMode    = 0x1000
+	flagPriority        = 0x0800
+	flagCorrelationID   = 0x0400
+	flagReplyTo         = 0x0200
+	flagExpiration      = 0x0100
+	flagMessageID       = 0x0080
+	flagTimestamp       = 0x0040
+	flagType            = 0x0020
+	flagUserID          = 0x0010
+	flagAppID           = 0x0008
+)
+
+// frame is an AMQP 0-9-1 frame.
+type frame struct {
+	kind    byte
+	channel uint16
+	payload []byte
+}
+
+// readFrame reads a frame whose payload doesn't exceed given size, if it's positive.
+func readFrame(reader *bufio.Reader, max uint32) (frame, error) {
+	header := make([]byte, 7)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return frame{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[3:])
+	if max > 0 && size > max {
+		return frame{}, ErrMalformedFrame
+	}
+
+	payload := make([]byte, size+1)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return frame{}, err
+	}
+	if payload[size] != frameEnd {
+		return frame{}, ErrMalformedFrame
+	}
+
+	return frame{
+		kind:    header[0],
+		channel: binary.BigEndian.Uint16(header[1:3]),
+		payload: payload[:size],
+	}, nil
+}
+
+// encode returns the wire representation of the frame.
+func (e frame) encode() []byte {
+	data := make([]byte, 0, len(e.payload)+8)
+	data = append(data, e.kind)
+	data = binary.BigEndian.AppendUint16(data, e.channel)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(e.payload)))
+	data = append(data, e.payload...)
+	data = append(data, frameEnd)
+
+	return data
+}
+
+// method returns the identifier and the arguments decoder of a method frame.
+func (e frame) method() (uint32, *decoder) {
+	arguments := &decoder{data: e.payload}
+	class := arguments.short()
+	method := arguments.short()
+
+	return uint32(class)<<16 | uint32(method), arguments
+}
+
+// newMethod returns an encoder for the arguments of given method.
+func newMethod(method uint32) *encoder {
+	arguments := &encoder{}
+	arguments.short(uint16(method >> 16))
+	arguments.short(uint16(method))
+
+	return arguments
+}
+
+// content is a message sent with a basic method: a content header frame, followed by body frames.
+type content struct {
+	size       uint64
+	properties amqp.Publishing
+}
+
+// decodeContent reads a content header frame payload.
+func decodeContent(payload []byte) (content, error) {
+	header := &decoder{data: payload}
+	header.short() // class
+	header.short() // weight
+	size := header.longlong()
+	flags := header.short()
+
+	properties := amqp.Publishing{}
+	if flags&flagContentType != 0 {
+		properties.ContentType = header.shortstr()
+	}
+	if flags&flagContentEncoding != 0 {
+		properties.ContentEncoding = header.shortstr()
+	}
+	if flags&flagHeaders != 0 {
+		properties.Headers = header.table()
+	}
+	if flags&flagDeliveryMode != 0 {
+		properties.DeliveryMode = header.octet()
+	}
+	if flags&flagPriority != 0 {
+		properties.Priority = header.octet()
+	}
+	decodeMetadata(header, flags, &properties)
+
+	return content{size: size, properties: properties}, header.err
+}
+
+// decodeMetadata reads the application properties of a content header.
+func decodeMetadata(header *decoder, flags uint16, properties *amqp.Publishing) {
+	for _, field := range []struct {
+		flag  uint16
+		value *string
+	}{
+		{flagCorrelationID, &properties.CorrelationId},
+		{flagReplyTo, &properties.ReplyTo},
+		{flagExpiration, &properties.Expiration},
+		{flagMessageID, &properties.MessageId},
+	} {
+		if flags&field.flag != 0 {
+			*field.value = header.shortstr()
+		}
+	}
+
+	if flags&flagTimestamp != 0 {
+		properties.Timestamp = header.timestamp()
+	}
+
+	for _, field := range []struct {
+		flag  uint16
+		value *string
+	}{
+		{flagType, &properties.Type},
+		{flagUserID, &properties.UserId},
+		{flagAppID, &properties.AppId},
+	} {
+		if flags&field.flag != 0 {
+			*field.value = header.shortstr()
+		}
+	}
+}
+
+// encodeContent returns the frames of a message with given properties and body,
+// whose payloads don't exceed given size, if it's positive.
+func encodeContent(channel uint16, properties amqp.Publishing, body []byte, max uint32) []frame {
+	header := &encoder{}
+	header.short(classBasic)
+	header.short(0)
+	header.longlong(uint64(len(body)))
+
+	flags := &encoder{}
+	values := &encoder{}
+	encodeProperties(flags, values, properties)
+
+	header.data = append(header.data, flags.data...)
+	header.data = append(header.data, values.data...)
+
+	frames := []frame{{kind: frameHeader, channel: channel, payload: header.data}}
+
+	size := len(body)
+	if max > 0 {
+		size = int(max)
+	}
+	for len(body) > 0 {
+		chunk := body
+		if len(chunk) > size {
+			chunk = chunk[:size]
+		}
+		frames = append(frames, frame{kind: frameBody, channel: channel, payload: chunk})
+		body = body[len(chunk):]
+	}
+
+	return frames
+}
+
+// encodeProperties writes the flags and the values of non-empty content properties.
+func encodeProperties(flags *encoder, values *encoder, properties amqp.Publishing) {
+	mask := uint16(0)
+	text := func(flag uint16, value string) {
+		if value != "" {
+			mask |= flag
+			values.shortstr(value)
+		}
+	}
+
+	text(flagContentType, properties.ContentType)
+	text(flagContentEncoding, properties.ContentEncoding)
+	if len(properties.Headers) > 0 {
+		mask |= flagHeaders
+		values.table(properties.Headers)
+	}
+	if properties.DeliveryMode != 0 {
+		mask |= flagDeliveryMode
+		values.octet(properties.DeliveryMode)
+	}
+	if properties.Priority != 0 {
+		mask |= flagPriority
+		values.octet(properties.Priority)
+	}
+	text(flagCorrelationID, properties.CorrelationId)
+	text(flagReplyTo, properties.ReplyTo)
+	text(flagExpiration, properties.Expiration)
+	text(flagMessageID, properties.MessageId)
+	if !properties.Timestamp.IsZero() {
+		mask |= flagTimestamp
+		values.timestamp(properties.Timestamp)
+	}
+	text(flagType, properties.Type)
+	text(flagUserID, properties.UserId)
+	text(flagAppID, properties.AppId)
+
+	flags.short(mask)
+}
+
+// propertiesOf returns the content properties of given delivery.
+func propertiesOf(delivery amqp.Delivery) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         delivery.Headers,
+		ContentType:     delivery.ContentType,
+		ContentEncoding: delivery.ContentEncoding,
+		DeliveryMode:    delivery.DeliveryMode,
+		Priority:        delivery.Priority,
+		CorrelationId:   delivery.CorrelationId,
+		ReplyTo:         delivery.ReplyTo,
+		Expiration:      delivery.Expiration,
+		MessageId:       delivery.MessageId,
+		Timestamp:       delivery.Timestamp,
+		Type:            delivery.Type,
+		UserId:          delivery.UserId,
+		AppId:           delivery.AppId,
+	}
+}
+
+// propertiesOfReturn returns the content properties of given returned message.
+func propertiesOfReturn(message amqp.Return) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         message.Headers,
+		ContentType:     message.ContentType,
+		ContentEncoding: message.ContentEncoding,
+		DeliveryMode:    message.DeliveryMode,
+		Priority:        message.Priority,
+		CorrelationId:   message.CorrelationId,
+		ReplyTo:         message.ReplyTo,
+		Expiration:      message.Expiration,
+		MessageId:       message.MessageId,
+		Timestamp:       message.Timestamp,
+		Type:            message.Type,
+		UserId:          message.UserId,
+		AppId:           message.AppId,
+	}
+}
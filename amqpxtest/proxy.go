@@ -0,0 +1,312 @@
+package amqpxtest
+
+import (
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// proxyBufferSize is the size of the chunks forwarded by a Proxy.
+const proxyBufferSize = 32 * 1024
+
+// Proxy is a TCP proxy which sits between a client and a broker, such as a Server, to simulate network
+// failures: latency, bandwidth limits, black holes, half-open connections and resets.
+// Faults can be changed at any time, and apply to every connection going through the proxy.
+type Proxy struct {
+	upstream  string
+	listener  net.Listener
+	mutex     sync.Mutex
+	links     map[*link]struct{}
+	latency   time.Duration
+	bandwidth int64
+	blackhole bool
+	closed    bool
+	group     sync.WaitGroup
+}
+
+// NewProxy starts a new Proxy which forwards connections to given upstream address, with given options.
+func NewProxy(upstream string, options ...ProxyOption) (*Proxy, error) {
+	if upstream == "" {
+		return nil, errors.Wrap(ErrProxyUpstreamRequired, ErrMessageCannotStartProxy)
+	}
+
+	opts := newProxyOptions()
+	for _, option := range options {
+		err := option.apply(&opts)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrMessageCannotStartProxy)
+		}
+	}
+
+	listener, err := net.Listen("tcp", opts.address)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrMessageCannotStartProxy)
+	}
+
+	proxy := &Proxy{
+		upstream: upstream,
+		listener: listener,
+		links:    map[*link]struct{}{},
+	}
+
+	proxy.group.Add(1)
+	go proxy.accept()
+
+	return proxy, nil
+}
+
+// Addr returns the address the proxy is listening on.
+func (e *Proxy) Addr() string {
+	return e.listener.Addr().String()
+}
+
+// URI returns given amqp URI, rewritten to connect through the proxy.
+func (e *Proxy) URI(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" {
+		return "", ErrInvalidProxyURI
+	}
+
+	parsed.Host = e.Addr()
+
+	return parsed.String(), nil
+}
+
+// Connections returns the number of connections going through the proxy.
+func (e *Proxy) Connections() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.links)
+}
+
+// SetLatency delays every chunk of data forwarded by the proxy, in both directions.
+func (e *Proxy) SetLatency(latency time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.latency = latency
+}
+
+// SetBandwidth limits the throughput of every connection to given bytes per second, in both directions.
+// A zero bandwidth removes the limit.
+func (e *Proxy) SetBandwidth(bandwidth int64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.bandwidth = bandwidth
+}
+
+// Blackhole configures if the proxy silently discards data, in both directions, while keeping connections open.
+// It simulates a hung socket, which is only detected with heartbeats or deadlines.
+// Since frames are lost, the connections going through the proxy are usually unusable afterwards.
+func (e *Proxy) Blackhole(enabled bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.blackhole = enabled
+}
+
+// HalfOpen closes the upstream side of every connection, while keeping the client side open: the broker sees
+// a closed connection, but the client doesn't, and its data is silently discarded until it gives up.
+func (e *Proxy) HalfOpen() {
+	for _, link := range e.snapshot() {
+		link.halfOpen()
+	}
+}
+
+// Reset closes every connection abruptly, with a TCP reset.
+func (e *Proxy) Reset() {
+	for _, link := range e.snapshot() {
+		link.reset()
+	}
+}
+
+// Close stops the proxy: every connection is closed, and the proxy stops listening.
+func (e *Proxy) Close() error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return nil
+	}
+
+	e.closed = true
+	err := e.listener.Close()
+	e.mutex.Unlock()
+
+	for _, link := range e.snapshot() {
+		link.close()
+	}
+
+	e.group.Wait()
+
+	return err
+}
+
+// snapshot returns the connections going through the proxy.
+func (e *Proxy) snapshot() []*link {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	links := make([]*link, 0, len(e.links))
+	for link := range e.links {
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// faults returns the faults currently applied by the proxy.
+func (e *Proxy) faults() (time.Duration, int64, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.latency, e.bandwidth, e.blackhole
+}
+
+// accept forwards incoming connections until the listener is closed.
+func (e *Proxy) accept() {
+	defer e.group.Done()
+
+	for {
+		downstream, err := e.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		upstream, err := net.DialTimeout("tcp", e.upstream, DefaultProxyDialTimeout)
+		if err != nil {
+			thr := downstream.Close()
+			_ = thr
+			continue
+		}
+
+		link := &link{
+			proxy:      e,
+			downstream: downstream,
+			upstream:   upstream,
+		}
+
+		e.mutex.Lock()
+		if e.closed {
+			e.mutex.Unlock()
+			link.close()
+			return
+		}
+
+		e.links[link] = struct{}{}
+		e.group.Add(2)
+		e.mutex.Unlock()
+
+		go link.pipe(downstream, upstream, true)
+		go link.pipe(upstream, downstream, false)
+	}
+}
+
+// remove forgets given connection.
+func (e *Proxy) remove(link *link) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.links, link)
+}
+
+// link is a connection going through a Proxy, from a client (downstream) to a broker (upstream).
+type link struct {
+	proxy      *Proxy
+	downstream net.Conn
+	upstream   net.Conn
+	mutex      sync.Mutex
+	half       bool
+	once       sync.Once
+}
+
+// pipe forwards data from given source to given destination, until one of them is closed.
+func (e *link) pipe(source net.Conn, destination net.Conn, fromClient bool) {
+	defer e.proxy.group.Done()
+
+	buffer := make([]byte, proxyBufferSize)
+	for {
+		n, err := source.Read(buffer)
+		if n > 0 {
+			thr := e.forward(destination, buffer[:n])
+			if thr != nil && !e.isHalfOpen() {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// On a half-open connection, the client side stays open until the client closes it.
+	if e.isHalfOpen() && !fromClient {
+		return
+	}
+
+	e.close()
+}
+
+// forward writes given data to given destination, with the faults currently applied by the proxy.
+func (e *link) forward(destination net.Conn, data []byte) error {
+	latency, bandwidth, blackhole := e.proxy.faults()
+	if blackhole || e.isHalfOpen() {
+		return nil
+	}
+
+	delay := latency
+	if bandwidth > 0 {
+		delay += time.Duration(int64(len(data)) * int64(time.Second) / bandwidth)
+	}
+	time.Sleep(delay)
+
+	_, err := destination.Write(data)
+	return err
+}
+
+// isHalfOpen returns if the upstream side of the connection was closed by the proxy.
+func (e *link) isHalfOpen() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.half
+}
+
+// halfOpen closes the upstream side of the connection only.
+func (e *link) halfOpen() {
+	e.mutex.Lock()
+	e.half = true
+	e.mutex.Unlock()
+
+	thr := e.upstream.Close()
+	_ = thr
+}
+
+// reset closes both sides of the connection with a TCP reset.
+func (e *link) reset() {
+	for _, conn := range []net.Conn{e.downstream, e.upstream} {
+		tcp, ok := conn.(*net.TCPConn)
+		if ok {
+			thr := tcp.SetLinger(0)
+			_ = thr
+		}
+	}
+
+	e.close()
+}
+
+// close closes both sides of the connection.
+func (e *link) close() {
+	e.once.Do(func() {
+		thr := e.downstream.Close()
+		_ = thr
+
+		thr = e.upstream.Close()
+		_ = thr
+
+		e.proxy.remove(e)
+	})
+}
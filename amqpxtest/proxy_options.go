@@ -0,0 +1,46 @@
+package amqpxtest
+
+import (
+	"time"
+)
+
+// Proxy default configuration.
+const (
+	// DefaultProxyAddress is the default address of a Proxy: a random port on the loopback interface.
+	DefaultProxyAddress = "127.0.0.1:0"
+
+	// DefaultProxyDialTimeout is the default timeout of a Proxy to connect to its upstream.
+	DefaultProxyDialTimeout = 5 * time.Second
+)
+
+// ProxyOption is used to define Proxy options.
+type ProxyOption interface {
+	apply(*proxyOptions) error
+}
+
+type proxyOption func(*proxyOptions) error
+
+func (o proxyOption) apply(instance *proxyOptions) error {
+	return o(instance)
+}
+
+type proxyOptions struct {
+	address string
+}
+
+func newProxyOptions() proxyOptions {
+	return proxyOptions{
+		address: DefaultProxyAddress,
+	}
+}
+
+// WithProxyAddress will configure a Proxy to listen on the given address.
+func WithProxyAddress(address string) ProxyOption {
+	return proxyOption(func(options *proxyOptions) error {
+		if address == "" {
+			return ErrProxyAddressRequired
+		}
+		options.address = address
+		return nil
+	})
+}
@@ -0,0 +1,222 @@
+package amqpxtest_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func NewProxy(t *testing.T, server *amqpxtest.Server) (*amqpxtest.Proxy, string) {
+	t.Helper()
+
+	proxy, err := amqpxtest.NewProxy(server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proxy.Close())
+	})
+
+	uri, err := proxy.URI(server.URI())
+	require.NoError(t, err)
+
+	return proxy, uri
+}
+
+type observer struct {
+	mutex  sync.Mutex
+	closes []error
+}
+
+func (e *observer) OnError(err error) {}
+
+func (e *observer) OnClose(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.closes = append(e.closes, err)
+}
+
+func (e *observer) Closes() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.closes)
+}
+
+func TestProxy_Forward(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	proxy, uri := NewProxy(t, server)
+
+	connection, err := amqp.Dial(uri)
+	is.NoError(err)
+	defer func() {
+		is.NoError(connection.Close())
+	}()
+	is.Equal(1, proxy.Connections())
+	is.Equal(1, server.Connections())
+
+	channel, err := connection.Channel()
+	is.NoError(err)
+	_, err = channel.QueueDeclare("proxy", false, false, false, false, nil)
+	is.NoError(err)
+
+	proxy.SetLatency(100 * time.Millisecond)
+	start := time.Now()
+	_, err = channel.QueueDeclarePassive("proxy", false, false, false, false, nil)
+	is.NoError(err)
+	is.True(time.Since(start) >= 200*time.Millisecond)
+
+	proxy.SetLatency(0)
+	proxy.SetBandwidth(512 * 1024)
+	body := bytes.Repeat([]byte("amqpx"), 50000)
+	start = time.Now()
+	is.NoError(channel.Publish("", "proxy", false, false, amqp.Publishing{Body: body}))
+	delivery, ok, err := channel.Get("proxy", true)
+	is.NoError(err)
+	is.True(ok)
+	is.Equal(body, delivery.Body)
+	is.True(time.Since(start) >= 800*time.Millisecond)
+}
+
+func TestProxy_Blackhole(t *testing.T) {
+	is := require.New(t)
+
+	// The default heartbeat is lowered, so the test runs quickly with the dialer defaults.
+	heartbeat := amqpx.DefaultDialerHeartbeat
+	amqpx.DefaultDialerHeartbeat = time.Second
+	t.Cleanup(func() {
+		amqpx.DefaultDialerHeartbeat = heartbeat
+	})
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	proxy, uri := NewProxy(t, server)
+
+	dialer, err := amqpx.SimpleDialer(uri, amqpx.WithDialerTimeout(time.Second))
+	is.NoError(err)
+	is.Equal(amqpx.DefaultDialerHeartbeat, dialer.Heartbeat())
+
+	observer := &observer{}
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1), amqpx.WithObserver(observer))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	// Wait for heartbeats to be exchanged, so the client tracks its read deadline.
+	time.Sleep(1500 * time.Millisecond)
+
+	proxy.Blackhole(true)
+	start := time.Now()
+
+	// The driver closes the connection after three heartbeat intervals without any frame.
+	is.Eventually(func() bool {
+		return observer.Closes() == 1
+	}, 10*time.Second, 20*time.Millisecond)
+	is.True(time.Since(start) < 4*amqpx.DefaultDialerHeartbeat, time.Since(start).String())
+
+	// While the proxy is a black hole, reconnections fail with the deadlines of the dialer.
+	_, err = client.Channel()
+	is.Error(err)
+
+	proxy.Blackhole(false)
+	is.Eventually(func() bool {
+		channel, err := client.Channel()
+		if err != nil {
+			return false
+		}
+		return channel.Close() == nil
+	}, 10*time.Second, 20*time.Millisecond)
+}
+
+func TestProxy_Blackhole_Handshake(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	proxy, uri := NewProxy(t, server)
+	proxy.Blackhole(true)
+
+	dialer, err := amqpx.SimpleDialer(uri, amqpx.WithDialerTimeout(500*time.Millisecond))
+	is.NoError(err)
+
+	start := time.Now()
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.Error(err)
+	is.Nil(client)
+	is.True(amqpx.IsRetryable(err))
+	is.True(time.Since(start) < 3*time.Second)
+
+	proxy.Blackhole(false)
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.NoError(err)
+	is.NoError(client.Close())
+}
+
+func TestProxy_HalfOpen(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker(), amqpxtest.WithServerHeartbeat(time.Second))
+	proxy, uri := NewProxy(t, server)
+
+	dialer, err := amqpx.SimpleDialer(uri, amqpx.WithDialerHeartbeat(time.Second))
+	is.NoError(err)
+
+	observer := &observer{}
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1), amqpx.WithObserver(observer))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	// Wait for heartbeats to be exchanged, so the client tracks its read deadline.
+	time.Sleep(1500 * time.Millisecond)
+
+	proxy.HalfOpen()
+	is.Eventually(func() bool {
+		return server.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+	is.Equal(0, observer.Closes())
+
+	is.Eventually(func() bool {
+		return observer.Closes() == 1 && server.Connections() == 1
+	}, 10*time.Second, 20*time.Millisecond)
+
+	is.Eventually(func() bool {
+		channel, err := client.Channel()
+		if err != nil {
+			return false
+		}
+		return channel.Close() == nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestProxy_Reset(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	proxy, uri := NewProxy(t, server)
+
+	connection, err := amqp.Dial(uri)
+	is.NoError(err)
+	closes := connection.NotifyClose(make(chan *amqp.Error, 1))
+
+	proxy.Reset()
+
+	select {
+	case exception := <-closes:
+		is.NotNil(exception)
+	case <-time.After(time.Second):
+		is.Fail("reset connection not detected")
+	}
+	is.Equal(0, proxy.Connections())
+
+	_, err = proxy.URI("127.0.0.1")
+	is.Equal(amqpxtest.ErrInvalidProxyURI, err)
+}
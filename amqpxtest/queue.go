@@ -0,0 +1,246 @@
+package amqpxtest
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// queue stores messages until they're delivered to one of its consumers, in a round-robin fashion.
+type queue struct {
+	name       string
+	durable    bool
+	autoDelete bool
+	owner      *Client
+	deleted    bool
+	messages   []*message
+	consumers  []*consumer
+	next       int
+}
+
+// state returns the current state of the queue.
+func (e *queue) state() amqp.Queue {
+	return amqp.Queue{
+		Name:      e.name,
+		Messages:  len(e.messages),
+		Consumers: len(e.consumers),
+	}
+}
+
+// shift removes the first message of the queue.
+func (e *queue) shift() *message {
+	message := e.messages[0]
+	e.messages[0] = nil
+	e.messages = e.messages[1:]
+
+	return message
+}
+
+// requeue puts given messages back at the head of the queue, in the same order.
+func (e *queue) requeue(messages ...*message) {
+	if e.deleted || len(messages) == 0 {
+		return
+	}
+
+	for _, message := range messages {
+		message.redelivered = true
+	}
+
+	e.messages = append(append([]*message{}, messages...), e.messages...)
+	e.dispatch()
+}
+
+// dispatch delivers as many messages as possible to consumers which didn't reach their prefetch limit.
+func (e *queue) dispatch() {
+	for len(e.messages) > 0 {
+		consumer := e.available()
+		if consumer == nil {
+			return
+		}
+
+		consumer.deliver(e, e.shift())
+	}
+}
+
+// available returns the next consumer which can receive a message, if any.
+func (e *queue) available() *consumer {
+	for i := range e.consumers {
+		idx := (e.next + i) % len(e.consumers)
+		consumer := e.consumers[idx]
+		if consumer.ready() {
+			e.next = idx + 1
+			return consumer
+		}
+	}
+
+	return nil
+}
+
+// removeConsumer detaches given consumer from the queue.
+func (e *queue) removeConsumer(consumer *consumer) {
+	for i := range e.consumers {
+		if e.consumers[i] == consumer {
+			e.consumers = append(e.consumers[:i], e.consumers[i+1:]...)
+			return
+		}
+	}
+}
+
+// message is a message published to a queue.
+type message struct {
+	exchange    string
+	key         string
+	publishing  amqp.Publishing
+	redelivered bool
+}
+
+// newMessage returns a new message with a copy of given publishing.
+func newMessage(exchange, key string, publishing amqp.Publishing) *message {
+	if publishing.Headers != nil {
+		headers := make(amqp.Table, len(publishing.Headers))
+		for key, value := range publishing.Headers {
+			headers[key] = value
+		}
+		publishing.Headers = headers
+	}
+	publishing.Body = append([]byte(nil), publishing.Body...)
+
+	return &message{
+		exchange:   exchange,
+		key:        key,
+		publishing: publishing,
+	}
+}
+
+// delivery returns the delivery of the message with given acknowledger, delivery tag and consumer tag.
+func (e *message) delivery(acknowledger amqp.Acknowledger, tag uint64, consumer string) amqp.Delivery {
+	return amqp.Delivery{
+		Acknowledger:    acknowledger,
+		Headers:         e.publishing.Headers,
+		ContentType:     e.publishing.ContentType,
+		ContentEncoding: e.publishing.ContentEncoding,
+		DeliveryMode:    e.publishing.DeliveryMode,
+		Priority:        e.publishing.Priority,
+		CorrelationId:   e.publishing.CorrelationId,
+		ReplyTo:         e.publishing.ReplyTo,
+		Expiration:      e.publishing.Expiration,
+		MessageId:       e.publishing.MessageId,
+		Timestamp:       e.publishing.Timestamp,
+		Type:            e.publishing.Type,
+		UserId:          e.publishing.UserId,
+		AppId:           e.publishing.AppId,
+		ConsumerTag:     consumer,
+		DeliveryTag:     tag,
+		Redelivered:     e.redelivered,
+		Exchange:        e.exchange,
+		RoutingKey:      e.key,
+		Body:            e.publishing.Body,
+	}
+}
+
+// pending is a message delivered on a channel, which is waiting for an acknowledgement.
+type pending struct {
+	tag      uint64
+	queue    *queue
+	message  *message
+	consumer *consumer
+	delivery amqp.Delivery
+}
+
+// consumer receives the messages of a queue on a channel.
+// Messages are buffered, so a slow consumer never blocks the broker.
+type consumer struct {
+	tag        string
+	channel    *Channel
+	queue      *queue
+	autoAck    bool
+	exclusive  bool
+	prefetch   int
+	unacked    int
+	buffer     []*pending
+	signal     chan struct{}
+	done       chan struct{}
+	deliveries chan amqp.Delivery
+}
+
+// ready returns if the consumer can receive another message.
+func (e *consumer) ready() bool {
+	return e.autoAck || e.prefetch <= 0 || e.unacked < e.prefetch
+}
+
+// deliver buffers given message for the consumer, and tracks it on the channel until it's acknowledged.
+func (e *consumer) deliver(queue *queue, message *message) {
+	tag := e.channel.nextDeliveryTag()
+	delivery := &pending{
+		tag:      tag,
+		queue:    queue,
+		message:  message,
+		consumer: e,
+		delivery: message.delivery(e.channel, tag, e.tag),
+	}
+
+	if !e.autoAck {
+		e.unacked++
+		e.channel.unacked[tag] = delivery
+	}
+
+	e.buffer = append(e.buffer, delivery)
+
+	select {
+	case e.signal <- struct{}{}:
+	default:
+	}
+}
+
+// cancel stops the consumer, and puts the buffered messages it didn't receive back in the queue.
+func (e *consumer) cancel() {
+	close(e.done)
+
+	for _, delivery := range e.buffer {
+		e.channel.forget(delivery)
+	}
+	e.queue.requeue(messagesOf(e.buffer)...)
+	e.buffer = nil
+}
+
+// run forwards buffered messages to the deliveries channel until the consumer is canceled.
+func (e *consumer) run(broker *Broker) {
+	defer close(e.deliveries)
+
+	for {
+		broker.lock()
+		if len(e.buffer) == 0 {
+			broker.unlock()
+
+			select {
+			case <-e.signal:
+				continue
+			case <-e.done:
+				return
+			}
+		}
+
+		delivery := e.buffer[0]
+		e.buffer = e.buffer[1:]
+		broker.unlock()
+
+		select {
+		case e.deliveries <- delivery.delivery:
+		case <-e.done:
+			broker.lock()
+			if e.channel.forget(delivery) || e.autoAck {
+				delivery.queue.requeue(delivery.message)
+			}
+			broker.unlock()
+			return
+		}
+	}
+}
+
+// messagesOf returns the messages of given pending deliveries.
+func messagesOf(deliveries []*pending) []*message {
+	messages := make([]*message, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		messages = append(messages, delivery.message)
+	}
+
+	return messages
+}
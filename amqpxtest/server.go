@@ -0,0 +1,159 @@
+package amqpxtest
+
+import (
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Server is a minimal AMQP 0-9-1 server backed by a Broker, which real amqp clients can connect to over TCP.
+// It supports connection and channel handshakes, heartbeats, exchanges, queues, bindings, publishing with
+// confirms, consuming, and acknowledgements.
+type Server struct {
+	broker      *Broker
+	options     serverOptions
+	listener    net.Listener
+	mutex       sync.Mutex
+	connections map[*serverConnection]struct{}
+	closed      bool
+	group       sync.WaitGroup
+}
+
+// NewServer starts a new Server for given Broker, with given options.
+func NewServer(broker *Broker, options ...ServerOption) (*Server, error) {
+	opts := newServerOptions()
+	for _, option := range options {
+		err := option.apply(&opts)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrMessageCannotStartServer)
+		}
+	}
+
+	listener, err := net.Listen("tcp", opts.address)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrMessageCannotStartServer)
+	}
+
+	server := &Server{
+		broker:      broker,
+		options:     opts,
+		listener:    listener,
+		connections: map[*serverConnection]struct{}{},
+	}
+
+	server.group.Add(1)
+	go server.accept()
+
+	return server, nil
+}
+
+// Broker returns the broker of the server.
+func (e *Server) Broker() *Broker {
+	return e.broker
+}
+
+// Addr returns the address the server is listening on.
+func (e *Server) Addr() string {
+	return e.listener.Addr().String()
+}
+
+// URI returns an amqp URI to connect to the server.
+func (e *Server) URI() string {
+	username := e.options.username
+	password := e.options.password
+	if username == "" {
+		username = "guest"
+		password = "guest"
+	}
+
+	uri := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(username, password),
+		Host:   e.Addr(),
+		Path:   "/",
+	}
+
+	return uri.String()
+}
+
+// Connections returns the number of open connections.
+func (e *Server) Connections() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.connections)
+}
+
+// Close stops the server like a RabbitMQ node being shut down: every connection is closed with a
+// CONNECTION_FORCED exception, and the server stops listening.
+func (e *Server) Close() error {
+	return e.stop(true)
+}
+
+// Kill stops the server like a RabbitMQ node crashing: every socket is closed without any exception,
+// and the server stops listening.
+func (e *Server) Kill() error {
+	return e.stop(false)
+}
+
+// stop closes the listener and every connection, then waits for their goroutines.
+func (e *Server) stop(graceful bool) error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return nil
+	}
+
+	e.closed = true
+	err := e.listener.Close()
+	for connection := range e.connections {
+		if graceful {
+			connection.close(newError(amqp.ConnectionForced, "CONNECTION_FORCED",
+				"broker forced connection closure with reason '%s'", "shutdown"))
+		} else {
+			connection.kill()
+		}
+	}
+	e.mutex.Unlock()
+
+	e.group.Wait()
+
+	return err
+}
+
+// accept serves incoming connections until the listener is closed.
+func (e *Server) accept() {
+	defer e.group.Done()
+
+	for {
+		conn, err := e.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		e.mutex.Lock()
+		if e.closed {
+			e.mutex.Unlock()
+			thr := conn.Close()
+			_ = thr
+			return
+		}
+
+		connection := newServerConnection(e, conn)
+		e.connections[connection] = struct{}{}
+		e.group.Add(1)
+		e.mutex.Unlock()
+
+		go func() {
+			defer e.group.Done()
+			connection.serve()
+
+			e.mutex.Lock()
+			delete(e.connections, connection)
+			e.mutex.Unlock()
+		}()
+	}
+}
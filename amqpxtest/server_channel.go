@@ -0,0 +1,558 @@
+package amqpxtest
+
+import (
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// channelHandlers are the handlers of the methods a client can send on an open channel.
+var channelHandlers = map[uint32]func(*serverChannel, *decoder) error{
+	methodChannelFlow:     (*serverChannel).channelFlow,
+	methodExchangeDeclare: (*serverChannel).exchangeDeclare,
+	methodExchangeDelete:  (*serverChannel).exchangeDelete,
+	methodExchangeBind:    (*serverChannel).exchangeBind,
+	methodExchangeUnbind:  (*serverChannel).exchangeUnbind,
+	methodQueueDeclare:    (*serverChannel).queueDeclare,
+	methodQueueBind:       (*serverChannel).queueBind,
+	methodQueuePurge:      (*serverChannel).queuePurge,
+	methodQueueDelete:     (*serverChannel).queueDelete,
+	methodQueueUnbind:     (*serverChannel).queueUnbind,
+	methodBasicQos:        (*serverChannel).basicQos,
+	methodBasicConsume:    (*serverChannel).basicConsume,
+	methodBasicCancel:     (*serverChannel).basicCancel,
+	methodBasicPublish:    (*serverChannel).basicPublish,
+	methodBasicGet:        (*serverChannel).basicGet,
+	methodBasicAck:        (*serverChannel).basicAck,
+	methodBasicReject:     (*serverChannel).basicReject,
+	methodBasicNack:       (*serverChannel).basicNack,
+	methodConfirmSelect:   (*serverChannel).confirmSelect,
+}
+
+// serverChannel serves an AMQP 0-9-1 channel with a Channel of the connection's client.
+type serverChannel struct {
+	id         uint16
+	connection *serverConnection
+	channel    *Channel
+	closing    bool
+	publishing *publishing
+	returns    chan amqp.Return
+	confirms   chan amqp.Confirmation
+}
+
+// publishing is a message being received from a client, after its basic.publish method.
+type publishing struct {
+	method    uint32
+	exchange  string
+	key       string
+	mandatory bool
+	immediate bool
+	header    bool
+	content   content
+	body      []byte
+}
+
+// newServerChannel opens a new channel on given connection.
+func newServerChannel(connection *serverConnection, id uint16) (*serverChannel, error) {
+	channel, err := connection.client.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &serverChannel{
+		id:         id,
+		connection: connection,
+		channel:    channel.(*Channel),
+	}
+
+	instance.returns = instance.channel.NotifyReturn(make(chan amqp.Return, 1))
+	cancellations := instance.channel.NotifyCancel(make(chan string, 16))
+
+	connection.group.Add(1)
+	go func() {
+		defer connection.group.Done()
+		for tag := range cancellations {
+			method := newMethod(methodBasicCancel)
+			method.shortstr(tag)
+			method.boolean(true)
+			thr := connection.writeMethod(id, method)
+			_ = thr
+		}
+	}()
+
+	return instance, nil
+}
+
+// handle handles a frame received on the channel.
+// It returns whether the channel is still open, and a connection exception if the frame is unexpected.
+func (e *serverChannel) handle(frame frame) (bool, *amqp.Error) {
+	if frame.kind != frameMethod {
+		return true, e.receive(frame)
+	}
+
+	method, arguments := frame.method()
+
+	switch {
+	case method == methodChannelClose:
+		thr := e.channel.Close()
+		_ = thr
+		thr = e.connection.writeMethod(e.id, newMethod(methodChannelCloseOk))
+		_ = thr
+		return false, nil
+	case method == methodChannelCloseOk:
+		return false, nil
+	case e.closing:
+		return true, nil
+	case e.publishing != nil:
+		return true, errUnexpectedFrame(frame)
+	}
+
+	handler, ok := channelHandlers[method]
+	if !ok {
+		return true, newError(amqp.NotImplemented, "NOT_IMPLEMENTED",
+			"method %d.%d is not implemented", method>>16, method&0xffff)
+	}
+
+	err := handler(e, arguments)
+	if arguments.err != nil {
+		return true, newError(amqp.FrameError, "FRAME_ERROR", "cannot decode method %d.%d", method>>16, method&0xffff)
+	}
+
+	e.fail(method, err)
+
+	return true, nil
+}
+
+// receive handles the content header and body frames of a message being published.
+func (e *serverChannel) receive(frame frame) *amqp.Error {
+	if e.closing {
+		return nil
+	}
+	if e.publishing == nil {
+		return errUnexpectedFrame(frame)
+	}
+
+	switch {
+	case frame.kind == frameHeader && !e.publishing.header:
+		content, err := decodeContent(frame.payload)
+		if err != nil {
+			return newError(amqp.FrameError, "FRAME_ERROR", "cannot decode content header")
+		}
+		e.publishing.header = true
+		e.publishing.content = content
+	case frame.kind == frameBody && e.publishing.header:
+		e.publishing.body = append(e.publishing.body, frame.payload...)
+	default:
+		return errUnexpectedFrame(frame)
+	}
+
+	if uint64(len(e.publishing.body)) > e.publishing.content.size {
+		return errUnexpectedFrame(frame)
+	}
+	if uint64(len(e.publishing.body)) == e.publishing.content.size {
+		publishing := e.publishing
+		e.publishing = nil
+		e.fail(publishing.method, e.publish(publishing))
+	}
+
+	return nil
+}
+
+// fail closes the channel if given method failed with a channel exception.
+func (e *serverChannel) fail(method uint32, err error) {
+	exception := &amqp.Error{}
+	if err == nil || !errors.As(err, &exception) {
+		return
+	}
+
+	e.closing = true
+
+	channelClose := newMethod(methodChannelClose)
+	channelClose.short(uint16(exception.Code))
+	channelClose.shortstr(exception.Reason)
+	channelClose.short(uint16(method >> 16))
+	channelClose.short(uint16(method))
+
+	thr := e.connection.writeMethod(e.id, channelClose)
+	_ = thr
+}
+
+// reply sends given method unless the client asked not to wait for it.
+func (e *serverChannel) reply(noWait bool, method *encoder) error {
+	if noWait {
+		return nil
+	}
+
+	thr := e.connection.writeMethod(e.id, method)
+	_ = thr
+
+	return nil
+}
+
+func (e *serverChannel) channelFlow(arguments *decoder) error {
+	active := arguments.boolean()
+
+	flowOk := newMethod(methodChannelFlowOk)
+	flowOk.boolean(active)
+
+	return e.reply(false, flowOk)
+}
+
+func (e *serverChannel) exchangeDeclare(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	kind := arguments.shortstr()
+	passive := arguments.boolean()
+	durable := arguments.boolean()
+	autoDelete := arguments.boolean()
+	internal := arguments.boolean()
+	noWait := arguments.boolean()
+	args := arguments.table()
+
+	declare := e.channel.ExchangeDeclare
+	if passive {
+		declare = e.channel.ExchangeDeclarePassive
+	}
+
+	err := declare(name, kind, durable, autoDelete, internal, noWait, args)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(noWait, newMethod(methodExchangeDeclareOk))
+}
+
+func (e *serverChannel) exchangeDelete(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	ifUnused := arguments.boolean()
+	noWait := arguments.boolean()
+
+	err := e.channel.ExchangeDelete(name, ifUnused, noWait)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(noWait, newMethod(methodExchangeDeleteOk))
+}
+
+func (e *serverChannel) exchangeBind(arguments *decoder) error {
+	arguments.short()
+	destination := arguments.shortstr()
+	source := arguments.shortstr()
+	key := arguments.shortstr()
+	noWait := arguments.boolean()
+	args := arguments.table()
+
+	err := e.channel.ExchangeBind(destination, key, source, noWait, args)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(noWait, newMethod(methodExchangeBindOk))
+}
+
+func (e *serverChannel) exchangeUnbind(arguments *decoder) error {
+	arguments.short()
+	destination := arguments.shortstr()
+	source := arguments.shortstr()
+	key := arguments.shortstr()
+	noWait := arguments.boolean()
+	args := arguments.table()
+
+	err := e.channel.ExchangeUnbind(destination, key, source, noWait, args)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(noWait, newMethod(methodExchangeUnbindOk))
+}
+
+func (e *serverChannel) queueDeclare(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	passive := arguments.boolean()
+	durable := arguments.boolean()
+	exclusive := arguments.boolean()
+	autoDelete := arguments.boolean()
+	noWait := arguments.boolean()
+	args := arguments.table()
+
+	declare := e.channel.QueueDeclare
+	if passive {
+		declare = e.channel.QueueDeclarePassive
+	}
+
+	queue, err := declare(name, durable, autoDelete, exclusive, noWait, args)
+	if err != nil {
+		return err
+	}
+
+	declareOk := newMethod(methodQueueDeclareOk)
+	declareOk.shortstr(queue.Name)
+	declareOk.long(uint32(queue.Messages))
+	declareOk.long(uint32(queue.Consumers))
+
+	return e.reply(noWait, declareOk)
+}
+
+func (e *serverChannel) queueBind(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	exchange := arguments.shortstr()
+	key := arguments.shortstr()
+	noWait := arguments.boolean()
+	args := arguments.table()
+
+	err := e.channel.QueueBind(name, key, exchange, noWait, args)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(noWait, newMethod(methodQueueBindOk))
+}
+
+func (e *serverChannel) queuePurge(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	noWait := arguments.boolean()
+
+	count, err := e.channel.QueuePurge(name, noWait)
+	if err != nil {
+		return err
+	}
+
+	purgeOk := newMethod(methodQueuePurgeOk)
+	purgeOk.long(uint32(count))
+
+	return e.reply(noWait, purgeOk)
+}
+
+func (e *serverChannel) queueDelete(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	ifUnused := arguments.boolean()
+	ifEmpty := arguments.boolean()
+	noWait := arguments.boolean()
+
+	count, err := e.channel.QueueDelete(name, ifUnused, ifEmpty, noWait)
+	if err != nil {
+		return err
+	}
+
+	deleteOk := newMethod(methodQueueDeleteOk)
+	deleteOk.long(uint32(count))
+
+	return e.reply(noWait, deleteOk)
+}
+
+func (e *serverChannel) queueUnbind(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	exchange := arguments.shortstr()
+	key := arguments.shortstr()
+	args := arguments.table()
+
+	err := e.channel.QueueUnbind(name, key, exchange, args)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(false, newMethod(methodQueueUnbindOk))
+}
+
+func (e *serverChannel) basicQos(arguments *decoder) error {
+	prefetchSize := arguments.long()
+	prefetchCount := arguments.short()
+	global := arguments.boolean()
+
+	err := e.channel.Qos(int(prefetchCount), int(prefetchSize), global)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(false, newMethod(methodBasicQosOk))
+}
+
+func (e *serverChannel) basicConsume(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	tag := arguments.shortstr()
+	noLocal := arguments.boolean()
+	noAck := arguments.boolean()
+	exclusive := arguments.boolean()
+	noWait := arguments.boolean()
+	args := arguments.table()
+
+	if tag == "" {
+		tag = generateName("amq.ctag-")
+	}
+
+	deliveries, err := e.channel.Consume(name, tag, noAck, exclusive, noLocal, noWait, args)
+	if err != nil {
+		return err
+	}
+
+	consumeOk := newMethod(methodBasicConsumeOk)
+	consumeOk.shortstr(tag)
+	thr := e.reply(noWait, consumeOk)
+	_ = thr
+
+	e.connection.group.Add(1)
+	go func() {
+		defer e.connection.group.Done()
+		for delivery := range deliveries {
+			deliver := newMethod(methodBasicDeliver)
+			deliver.shortstr(delivery.ConsumerTag)
+			deliver.longlong(delivery.DeliveryTag)
+			deliver.boolean(delivery.Redelivered)
+			deliver.shortstr(delivery.Exchange)
+			deliver.shortstr(delivery.RoutingKey)
+
+			thr := e.connection.writeContent(e.id, deliver, propertiesOf(delivery), delivery.Body)
+			_ = thr
+		}
+	}()
+
+	return nil
+}
+
+func (e *serverChannel) basicCancel(arguments *decoder) error {
+	tag := arguments.shortstr()
+	noWait := arguments.boolean()
+
+	err := e.channel.Cancel(tag, noWait)
+	if err != nil {
+		return err
+	}
+
+	cancelOk := newMethod(methodBasicCancelOk)
+	cancelOk.shortstr(tag)
+
+	return e.reply(noWait, cancelOk)
+}
+
+func (e *serverChannel) basicPublish(arguments *decoder) error {
+	arguments.short()
+	e.publishing = &publishing{
+		method:    methodBasicPublish,
+		exchange:  arguments.shortstr(),
+		key:       arguments.shortstr(),
+		mandatory: arguments.boolean(),
+		immediate: arguments.boolean(),
+	}
+
+	return nil
+}
+
+// publish publishes a message received from the client, then sends its return and its confirmation, if any.
+func (e *serverChannel) publish(publishing *publishing) error {
+	message := publishing.content.properties
+	message.Body = publishing.body
+
+	err := e.channel.Publish(publishing.exchange, publishing.key, publishing.mandatory, publishing.immediate, message)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case returned, ok := <-e.returns:
+		if ok {
+			method := newMethod(methodBasicReturn)
+			method.short(returned.ReplyCode)
+			method.shortstr(returned.ReplyText)
+			method.shortstr(returned.Exchange)
+			method.shortstr(returned.RoutingKey)
+
+			thr := e.connection.writeContent(e.id, method, propertiesOfReturn(returned), returned.Body)
+			_ = thr
+		}
+	default:
+	}
+
+	select {
+	case confirmation, ok := <-e.confirms:
+		if ok {
+			method := newMethod(methodBasicAck)
+			method.longlong(confirmation.DeliveryTag)
+			method.boolean(false)
+
+			thr := e.connection.writeMethod(e.id, method)
+			_ = thr
+		}
+	default:
+	}
+
+	return nil
+}
+
+func (e *serverChannel) basicGet(arguments *decoder) error {
+	arguments.short()
+	name := arguments.shortstr()
+	noAck := arguments.boolean()
+
+	delivery, ok, err := e.channel.Get(name, noAck)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		getEmpty := newMethod(methodBasicGetEmpty)
+		getEmpty.shortstr("")
+		return e.reply(false, getEmpty)
+	}
+
+	getOk := newMethod(methodBasicGetOk)
+	getOk.longlong(delivery.DeliveryTag)
+	getOk.boolean(delivery.Redelivered)
+	getOk.shortstr(delivery.Exchange)
+	getOk.shortstr(delivery.RoutingKey)
+	getOk.long(delivery.MessageCount)
+
+	thr := e.connection.writeContent(e.id, getOk, propertiesOf(delivery), delivery.Body)
+	_ = thr
+
+	return nil
+}
+
+func (e *serverChannel) basicAck(arguments *decoder) error {
+	tag := arguments.longlong()
+	multiple := arguments.boolean()
+
+	return e.channel.Ack(tag, multiple)
+}
+
+func (e *serverChannel) basicReject(arguments *decoder) error {
+	tag := arguments.longlong()
+	requeue := arguments.boolean()
+
+	return e.channel.Reject(tag, requeue)
+}
+
+func (e *serverChannel) basicNack(arguments *decoder) error {
+	tag := arguments.longlong()
+	multiple := arguments.boolean()
+	requeue := arguments.boolean()
+
+	return e.channel.Nack(tag, multiple, requeue)
+}
+
+func (e *serverChannel) confirmSelect(arguments *decoder) error {
+	noWait := arguments.boolean()
+
+	if e.confirms == nil {
+		e.confirms = e.channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
+	err := e.channel.Confirm(noWait)
+	if err != nil {
+		return err
+	}
+
+	return e.reply(noWait, newMethod(methodConfirmSelectOk))
+}
+
+// errUnexpectedFrame returns the connection exception for a frame received out of sequence.
+func errUnexpectedFrame(frame frame) *amqp.Error {
+	return newError(amqp.UnexpectedFrame, "UNEXPECTED_FRAME", "unexpected frame of type %d on channel %d",
+		frame.kind, frame.channel)
+}
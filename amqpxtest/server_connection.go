@@ -0,0 +1,457 @@
+package amqpxtest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Server connection timeouts.
+const (
+	// handshakeTimeout is the time allowed to a client to complete the connection handshake.
+	handshakeTimeout = 10 * time.Second
+
+	// closeTimeout is the time allowed to a client to acknowledge a connection closed by the server.
+	closeTimeout = time.Second
+)
+
+// serverProperties are the properties sent by the server in connection.start.
+var serverProperties = amqp.Table{
+	"product": "amqpxtest",
+	"version": "3",
+	"capabilities": amqp.Table{
+		"publisher_confirms":         true,
+		"exchange_exchange_bindings": true,
+		"basic.nack":                 true,
+		"consumer_cancel_notify":     true,
+		"per_consumer_qos":           true,
+	},
+}
+
+// serverConnection serves an AMQP 0-9-1 connection with a Client of the server's broker.
+type serverConnection struct {
+	server    *Server
+	conn      net.Conn
+	reader    *bufio.Reader
+	mutex     sync.Mutex
+	closing   bool
+	client    *Client
+	channels  map[uint16]*serverChannel
+	frameMax  uint32
+	heartbeat time.Duration
+	group     sync.WaitGroup
+	done      chan struct{}
+}
+
+// newServerConnection returns a new serverConnection for given socket.
+func newServerConnection(server *Server, conn net.Conn) *serverConnection {
+	return &serverConnection{
+		server:   server,
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		client:   NewClient(server.broker),
+		channels: map[uint16]*serverChannel{},
+		frameMax: DefaultServerFrameMax,
+		done:     make(chan struct{}),
+	}
+}
+
+// serve runs the connection until it's closed, by the client or by the server.
+func (e *serverConnection) serve() {
+	defer e.terminate()
+
+	err := e.handshake()
+	if err != nil {
+		return
+	}
+
+	if e.heartbeat > 0 {
+		e.group.Add(1)
+		go e.heartbeater()
+	}
+
+	e.loop()
+}
+
+// handshake negotiates the connection parameters, authenticates the client and opens the virtual host.
+func (e *serverConnection) handshake() error {
+	err := e.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, len(protocolHeader))
+	_, err = io.ReadFull(e.reader, header)
+	if err != nil {
+		return err
+	}
+	if string(header) != protocolHeader {
+		_, err = e.conn.Write([]byte(protocolHeader))
+		if err != nil {
+			return err
+		}
+		return ErrMalformedFrame
+	}
+
+	err = e.start()
+	if err != nil {
+		return err
+	}
+
+	err = e.tune()
+	if err != nil {
+		return err
+	}
+
+	err = e.open()
+	if err != nil {
+		return err
+	}
+
+	return e.conn.SetDeadline(time.Time{})
+}
+
+// start sends connection.start, and authenticates the client with its connection.start-ok.
+func (e *serverConnection) start() error {
+	start := newMethod(methodConnectionStart)
+	start.octet(0)
+	start.octet(9)
+	start.table(serverProperties)
+	start.longstr("PLAIN AMQPLAIN")
+	start.longstr("en_US")
+
+	err := e.writeMethod(0, start)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := e.expect(methodConnectionStartOk)
+	if err != nil {
+		return err
+	}
+
+	arguments.table()
+	mechanism := arguments.shortstr()
+	response := arguments.longstr()
+
+	if !e.authenticate(mechanism, response) {
+		exception := newError(amqp.AccessRefused, "ACCESS_REFUSED",
+			"Login was refused using authentication mechanism %s", mechanism)
+		e.close(exception)
+		e.drain()
+		return exception
+	}
+
+	return arguments.err
+}
+
+// authenticate returns if given SASL response holds the credentials expected by the server.
+func (e *serverConnection) authenticate(mechanism string, response string) bool {
+	username := ""
+	password := ""
+
+	switch mechanism {
+	case "PLAIN":
+		parts := strings.Split(response, "\x00")
+		if len(parts) != 3 {
+			return false
+		}
+		username, password = parts[1], parts[2]
+	case "AMQPLAIN":
+		encoded := &encoder{}
+		encoded.longstr(response)
+		table := (&decoder{data: encoded.data}).table()
+		username, _ = table["LOGIN"].(string)
+		password, _ = table["PASSWORD"].(string)
+	default:
+		return false
+	}
+
+	options := e.server.options
+	return options.username == "" || (username == options.username && password == options.password)
+}
+
+// tune sends connection.tune, and applies the parameters of the client's connection.tune-ok.
+func (e *serverConnection) tune() error {
+	tune := newMethod(methodConnectionTune)
+	tune.short(uint16(e.server.options.channelMax))
+	tune.long(DefaultServerFrameMax)
+	tune.short(uint16(e.server.options.heartbeat / time.Second))
+
+	err := e.writeMethod(0, tune)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := e.expect(methodConnectionTuneOk)
+	if err != nil {
+		return err
+	}
+
+	arguments.short()
+	frameMax := arguments.long()
+	heartbeat := arguments.short()
+
+	if frameMax > 0 && frameMax < e.frameMax {
+		e.frameMax = frameMax
+	}
+	e.heartbeat = time.Duration(heartbeat) * time.Second
+
+	return arguments.err
+}
+
+// open checks the virtual host requested by the client's connection.open, and sends connection.open-ok.
+func (e *serverConnection) open() error {
+	arguments, err := e.expect(methodConnectionOpen)
+	if err != nil {
+		return err
+	}
+
+	name := arguments.shortstr()
+	if arguments.err != nil {
+		return arguments.err
+	}
+	if name != vhost {
+		exception := newError(amqp.NotAllowed, "NOT_ALLOWED", "vhost %s not found", name)
+		e.close(exception)
+		e.drain()
+		return exception
+	}
+
+	openOk := newMethod(methodConnectionOpenOk)
+	openOk.shortstr("")
+
+	return e.writeMethod(0, openOk)
+}
+
+// expect reads the next method frame on channel zero, and checks it's given method.
+func (e *serverConnection) expect(method uint32) (*decoder, error) {
+	for {
+		frame, err := readFrame(e.reader, e.frameMax)
+		if err != nil {
+			return nil, err
+		}
+		if frame.kind == frameHeartbeat {
+			continue
+		}
+
+		current, arguments := frame.method()
+		if frame.kind != frameMethod || frame.channel != 0 || current != method {
+			return nil, ErrMalformedFrame
+		}
+
+		return arguments, nil
+	}
+}
+
+// loop reads and handles frames until the connection is closed.
+func (e *serverConnection) loop() {
+	for {
+		err := e.resetDeadline()
+		if err != nil {
+			return
+		}
+
+		frame, err := readFrame(e.reader, e.frameMax)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case frame.kind == frameHeartbeat:
+		case frame.channel == 0:
+			if e.handleConnection(frame) {
+				return
+			}
+		case e.isClosing():
+		default:
+			exception := e.dispatch(frame)
+			if exception != nil {
+				e.close(exception)
+			}
+		}
+	}
+}
+
+// resetDeadline extends the read deadline of the socket, when heartbeats are enabled.
+// A client which misses two heartbeats is considered dead.
+func (e *serverConnection) resetDeadline() error {
+	if e.heartbeat <= 0 || e.isClosing() {
+		return nil
+	}
+
+	return e.conn.SetReadDeadline(time.Now().Add(2 * e.heartbeat))
+}
+
+// handleConnection handles a method frame on channel zero, and returns if the connection is closed.
+func (e *serverConnection) handleConnection(frame frame) bool {
+	method, _ := frame.method()
+
+	switch method {
+	case methodConnectionClose:
+		thr := e.writeMethod(0, newMethod(methodConnectionCloseOk))
+		_ = thr
+		return true
+	case methodConnectionCloseOk:
+		return true
+	default:
+		if !e.isClosing() {
+			e.close(newError(amqp.CommandInvalid, "COMMAND_INVALID", "unexpected method on channel 0"))
+		}
+		return false
+	}
+}
+
+// dispatch handles a frame on a channel, and returns a connection exception if the frame is unexpected.
+func (e *serverConnection) dispatch(frame frame) *amqp.Error {
+	channel, ok := e.channels[frame.channel]
+	if ok {
+		open, exception := channel.handle(frame)
+		if !open {
+			delete(e.channels, frame.channel)
+		}
+		return exception
+	}
+
+	method, _ := frame.method()
+	if frame.kind != frameMethod || method != methodChannelOpen {
+		return newError(amqp.ChannelError, "CHANNEL_ERROR", "expected 'channel.open' on channel %d", frame.channel)
+	}
+	if len(e.channels) >= e.server.options.channelMax {
+		return newError(amqp.NotAllowed, "NOT_ALLOWED",
+			"number of channels opened (%d) has reached the negotiated channel_max (%d)",
+			len(e.channels), e.server.options.channelMax)
+	}
+
+	channel, err := newServerChannel(e, frame.channel)
+	if err != nil {
+		return newError(amqp.InternalError, "INTERNAL_ERROR", "%s", err)
+	}
+	e.channels[frame.channel] = channel
+
+	openOk := newMethod(methodChannelOpenOk)
+	openOk.longstr("")
+	thr := e.writeMethod(frame.channel, openOk)
+	_ = thr
+
+	return nil
+}
+
+// heartbeater sends heartbeat frames at half the negotiated interval, until the connection is terminated.
+func (e *serverConnection) heartbeater() {
+	defer e.group.Done()
+
+	ticker := time.NewTicker(e.heartbeat / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := e.write(frame{kind: frameHeartbeat})
+			if err != nil {
+				return
+			}
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// writeMethod sends a method frame with given arguments on given channel.
+func (e *serverConnection) writeMethod(channel uint16, arguments *encoder) error {
+	return e.write(frame{kind: frameMethod, channel: channel, payload: arguments.data})
+}
+
+// writeContent sends a method frame followed by a message, atomically.
+func (e *serverConnection) writeContent(channel uint16, arguments *encoder, properties amqp.Publishing,
+	body []byte) error {
+
+	frames := []frame{{kind: frameMethod, channel: channel, payload: arguments.data}}
+	frames = append(frames, encodeContent(channel, properties, body, e.frameMax-8)...)
+
+	return e.write(frames...)
+}
+
+// write sends given frames, atomically.
+func (e *serverConnection) write(frames ...frame) error {
+	data := []byte{}
+	for _, frame := range frames {
+		data = append(data, frame.encode()...)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	_, err := e.conn.Write(data)
+	return err
+}
+
+// isClosing returns if the server closed the connection and is waiting for the client's acknowledgement.
+func (e *serverConnection) isClosing() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.closing
+}
+
+// close closes the connection with given exception.
+// The socket is closed once the client acknowledges it, or after a timeout.
+func (e *serverConnection) close(exception *amqp.Error) {
+	e.mutex.Lock()
+	if e.closing {
+		e.mutex.Unlock()
+		return
+	}
+	e.closing = true
+	e.mutex.Unlock()
+
+	method := newMethod(methodConnectionClose)
+	method.short(uint16(exception.Code))
+	method.shortstr(exception.Reason)
+	method.short(0)
+	method.short(0)
+
+	thr := e.writeMethod(0, method)
+	_ = thr
+
+	thr = e.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+	_ = thr
+}
+
+// drain reads frames until the client acknowledges a connection closed by the server.
+func (e *serverConnection) drain() {
+	for {
+		frame, err := readFrame(e.reader, e.frameMax)
+		if err != nil {
+			return
+		}
+
+		method, _ := frame.method()
+		if frame.kind == frameMethod && frame.channel == 0 && method == methodConnectionCloseOk {
+			return
+		}
+	}
+}
+
+// kill closes the socket without any exception.
+func (e *serverConnection) kill() {
+	thr := e.conn.Close()
+	_ = thr
+}
+
+// terminate closes the socket and the client of the connection, then waits for its goroutines.
+// Like with RabbitMQ, unacknowledged messages are requeued and exclusive queues are deleted.
+func (e *serverConnection) terminate() {
+	close(e.done)
+	e.kill()
+
+	thr := e.client.Close()
+	_ = thr
+
+	e.group.Wait()
+}
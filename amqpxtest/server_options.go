@@ -0,0 +1,92 @@
+package amqpxtest
+
+import (
+	"time"
+)
+
+// Server default configuration.
+const (
+	// DefaultServerAddress is the default address of a Server: a random port on the loopback interface.
+	DefaultServerAddress = "127.0.0.1:0"
+
+	// DefaultServerHeartbeat is the default heartbeat interval proposed by a Server.
+	DefaultServerHeartbeat = 60 * time.Second
+
+	// DefaultServerChannelMax is the default maximum number of channels per connection of a Server.
+	DefaultServerChannelMax = 2047
+
+	// DefaultServerFrameMax is the default maximum frame size of a Server.
+	DefaultServerFrameMax = 131072
+)
+
+// ServerOption is used to define Server options.
+type ServerOption interface {
+	apply(*serverOptions) error
+}
+
+type serverOption func(*serverOptions) error
+
+func (o serverOption) apply(instance *serverOptions) error {
+	return o(instance)
+}
+
+type serverOptions struct {
+	address    string
+	heartbeat  time.Duration
+	channelMax int
+	username   string
+	password   string
+}
+
+func newServerOptions() serverOptions {
+	return serverOptions{
+		address:    DefaultServerAddress,
+		heartbeat:  DefaultServerHeartbeat,
+		channelMax: DefaultServerChannelMax,
+	}
+}
+
+// WithServerAddress will configure a Server to listen on the given address.
+// It allows to restart a server on the address of a previous one.
+func WithServerAddress(address string) ServerOption {
+	return serverOption(func(options *serverOptions) error {
+		if address == "" {
+			return ErrServerAddressRequired
+		}
+		options.address = address
+		return nil
+	})
+}
+
+// WithServerHeartbeat will configure a Server with the given heartbeat interval.
+// A zero interval disables heartbeats, unless the client requests them.
+func WithServerHeartbeat(heartbeat time.Duration) ServerOption {
+	return serverOption(func(options *serverOptions) error {
+		if heartbeat < 0 || heartbeat%time.Second != 0 {
+			return ErrInvalidServerHeartbeat
+		}
+		options.heartbeat = heartbeat
+		return nil
+	})
+}
+
+// WithServerChannelMax will configure a Server with the given maximum number of channels per connection.
+func WithServerChannelMax(channelMax int) ServerOption {
+	return serverOption(func(options *serverOptions) error {
+		if channelMax <= 0 || channelMax > 65535 {
+			return ErrInvalidServerChannelMax
+		}
+		options.channelMax = channelMax
+		return nil
+	})
+}
+
+// WithServerCredentials will configure a Server to only accept the given credentials.
+// By default, any credentials are accepted.
+func WithServerCredentials(username, password string) ServerOption {
+	return serverOption(func(options *serverOptions) error {
+		options.username = username
+		options.password = password
+		return nil
+	})
+}
@@ -0,0 +1,271 @@
+package amqpxtest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func NewServer(t *testing.T, broker *amqpxtest.Broker, options ...amqpxtest.ServerOption) *amqpxtest.Server {
+	t.Helper()
+
+	server, err := amqpxtest.NewServer(broker, options...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, server.Kill())
+	})
+
+	return server
+}
+
+func TestServer_PublishConsume(t *testing.T) {
+	is := require.New(t)
+
+	broker := amqpxtest.NewBroker()
+	server := NewServer(t, broker)
+
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+	is.Equal(2, server.Connections())
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	is.NoError(channel.ExchangeDeclare("events", amqpx.ExchangeTopic, true, false, false, false, nil))
+	queue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	is.NoError(err)
+	is.True(strings.HasPrefix(queue.Name, "amq.gen-"))
+	is.NoError(channel.QueueBind(queue.Name, "orders.#", "events", false, nil))
+
+	is.NoError(channel.Qos(1, 0, false))
+	deliveries, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	is.NoError(err)
+
+	timestamp := time.Unix(time.Now().Unix(), 0)
+	body := []byte(strings.Repeat("amqpx", 100000))
+	is.NoError(channel.Publish("events", "orders.created", false, false, amqp.Publishing{
+		Headers:      amqp.Table{"tenant": "ulule", "attempt": int32(1), "tags": []interface{}{"a", "b"}},
+		ContentType:  "text/plain",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    "42",
+		Timestamp:    timestamp,
+		AppId:        "amqpxtest",
+		Body:         body,
+	}))
+	is.NoError(channel.Publish("events", "orders.updated", false, false, amqp.Publishing{Body: []byte("updated")}))
+
+	delivery := receive(t, deliveries)
+	is.Equal("orders.created", delivery.RoutingKey)
+	is.Equal("events", delivery.Exchange)
+	is.Equal(amqp.Table{"tenant": "ulule", "attempt": int32(1), "tags": []interface{}{"a", "b"}}, delivery.Headers)
+	is.Equal("text/plain", delivery.ContentType)
+	is.Equal(uint8(amqp.Persistent), delivery.DeliveryMode)
+	is.Equal("42", delivery.MessageId)
+	is.True(timestamp.Equal(delivery.Timestamp))
+	is.Equal("amqpxtest", delivery.AppId)
+	is.Equal(body, delivery.Body)
+	is.NoError(delivery.Nack(false, true))
+
+	delivery = receive(t, deliveries)
+	is.Equal("orders.created", delivery.RoutingKey)
+	is.True(delivery.Redelivered)
+	is.NoError(delivery.Ack(false))
+
+	delivery = receive(t, deliveries)
+	is.Equal("updated", string(delivery.Body))
+	is.NoError(delivery.Ack(false))
+
+	is.NoError(channel.Close())
+}
+
+func TestServer_Confirm(t *testing.T) {
+	is := require.New(t)
+
+	broker := amqpxtest.NewBroker()
+	server := NewServer(t, broker)
+
+	connection, err := amqp.Dial(server.URI())
+	is.NoError(err)
+	defer func() {
+		is.NoError(connection.Close())
+	}()
+
+	channel, err := connection.Channel()
+	is.NoError(err)
+
+	_, err = channel.QueueDeclare("confirm", true, false, false, false, nil)
+	is.NoError(err)
+
+	is.NoError(channel.Confirm(false))
+	confirms := channel.NotifyPublish(make(chan amqp.Confirmation, 2))
+	returns := channel.NotifyReturn(make(chan amqp.Return, 1))
+
+	is.NoError(channel.Publish("", "unknown", true, false, amqp.Publishing{Body: []byte("lost")}))
+	is.NoError(channel.Publish("", "confirm", true, false, amqp.Publishing{Body: []byte("routed")}))
+
+	returned := <-returns
+	is.Equal(uint16(amqp.NoRoute), returned.ReplyCode)
+	is.Equal("unknown", returned.RoutingKey)
+	is.Equal("lost", string(returned.Body))
+
+	is.Equal(amqp.Confirmation{DeliveryTag: 1, Ack: true}, <-confirms)
+	is.Equal(amqp.Confirmation{DeliveryTag: 2, Ack: true}, <-confirms)
+
+	delivery, ok, err := channel.Get("confirm", false)
+	is.NoError(err)
+	is.True(ok)
+	is.Equal("routed", string(delivery.Body))
+	is.NoError(delivery.Ack(false))
+
+	_, ok, err = channel.Get("confirm", true)
+	is.NoError(err)
+	is.False(ok)
+
+	count, err := channel.QueueDelete("confirm", false, false, false)
+	is.NoError(err)
+	is.Equal(0, count)
+}
+
+func TestServer_Exception(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+
+	connection, err := amqp.Dial(server.URI())
+	is.NoError(err)
+	defer func() {
+		is.NoError(connection.Close())
+	}()
+
+	channel, err := connection.Channel()
+	is.NoError(err)
+	closes := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	_, err = channel.QueueDeclarePassive("unknown", false, false, false, false, nil)
+	is.Error(err)
+
+	exception := <-closes
+	is.NotNil(exception)
+	is.Equal(amqp.NotFound, exception.Code)
+	is.Equal("NOT_FOUND - no queue 'unknown' in vhost '/'", exception.Reason)
+	is.True(channel.IsClosed())
+	is.False(connection.IsClosed())
+
+	channel, err = connection.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+}
+
+func TestServer_Credentials(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker(), amqpxtest.WithServerCredentials("amqpx", "secret"))
+	is.True(strings.HasPrefix(server.URI(), "amqp://amqpx:secret@"))
+
+	connection, err := amqp.Dial(server.URI())
+	is.NoError(err)
+	is.NoError(connection.Close())
+
+	dialer, err := amqpx.SimpleDialer(strings.Replace(server.URI(), "secret", "invalid", 1))
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrAccessRefused))
+	is.False(amqpx.IsRetryable(err))
+
+	_, err = amqp.Dial(server.URI() + "amqpx")
+	is.Equal(amqp.ErrVhost, err)
+}
+
+func TestServer_Close(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+
+	connection, err := amqp.Dial(server.URI())
+	is.NoError(err)
+	closes := connection.NotifyClose(make(chan *amqp.Error, 1))
+
+	is.NoError(server.Close())
+	is.NoError(server.Close())
+
+	exception := <-closes
+	is.NotNil(exception)
+	is.Equal(amqp.ConnectionForced, exception.Code)
+	is.True(exception.Server)
+	is.True(connection.IsClosed())
+	is.Equal(0, server.Connections())
+}
+
+func TestServer_Heartbeat(t *testing.T) {
+	is := require.New(t)
+
+	server := NewServer(t, amqpxtest.NewBroker(), amqpxtest.WithServerHeartbeat(time.Second))
+
+	connection, err := amqp.DialConfig(server.URI(), amqp.Config{Heartbeat: time.Second})
+	is.NoError(err)
+	defer func() {
+		is.NoError(connection.Close())
+	}()
+
+	time.Sleep(3 * time.Second)
+
+	is.False(connection.IsClosed())
+	is.Equal(1, server.Connections())
+}
+
+func TestServer_Reconnect(t *testing.T) {
+	is := require.New(t)
+
+	broker := amqpxtest.NewBroker()
+	server, err := amqpxtest.NewServer(broker)
+	is.NoError(err)
+
+	other := NewServer(t, broker)
+	dialer, err := amqpx.ClusterDialer([]string{server.URI(), other.URI()})
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(4))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+	is.Equal(2, server.Connections())
+	is.Equal(2, other.Connections())
+
+	address := server.Addr()
+	is.NoError(server.Kill())
+
+	server, err = amqpxtest.NewServer(broker, amqpxtest.WithServerAddress(address))
+	is.NoError(err)
+	defer func() {
+		is.NoError(server.Kill())
+	}()
+
+	is.Eventually(func() bool {
+		return server.Connections() == 2
+	}, 10*time.Second, 50*time.Millisecond)
+
+	for i := 0; i < 8; i++ {
+		channel, err := client.Channel()
+		is.NoError(err)
+		_, err = channel.QueueDeclare("reconnect", true, false, false, false, nil)
+		is.NoError(err)
+		is.NoError(channel.Close())
+	}
+}
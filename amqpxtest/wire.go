@@ -0,0 +1,309 @@
+package amqpxtest
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// decoder reads AMQP 0-9-1 data types from a frame payload.
+// Consecutive bits are packed in octets, and the first error is sticky.
+type decoder struct {
+	data []byte
+	bits byte
+	bit  uint
+	err  error
+}
+
+// take consumes given number of bytes.
+func (e *decoder) take(size int) []byte {
+	e.bit = 0
+	if e.err != nil {
+		return nil
+	}
+	if size < 0 || size > len(e.data) {
+		e.err = ErrMalformedFrame
+		e.data = nil
+		return nil
+	}
+
+	value := e.data[:size]
+	e.data = e.data[size:]
+
+	return value
+}
+
+func (e *decoder) octet() byte {
+	value := e.take(1)
+	if value == nil {
+		return 0
+	}
+	return value[0]
+}
+
+func (e *decoder) short() uint16 {
+	value := e.take(2)
+	if value == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(value)
+}
+
+func (e *decoder) long() uint32 {
+	value := e.take(4)
+	if value == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(value)
+}
+
+func (e *decoder) longlong() uint64 {
+	value := e.take(8)
+	if value == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+func (e *decoder) shortstr() string {
+	return string(e.take(int(e.octet())))
+}
+
+func (e *decoder) longstr() string {
+	return string(e.take(int(e.long())))
+}
+
+func (e *decoder) timestamp() time.Time {
+	return time.Unix(int64(e.longlong()), 0)
+}
+
+func (e *decoder) boolean() bool {
+	if e.bit == 0 || e.bit == 8 {
+		e.bits = e.octet()
+	}
+
+	value := e.bits&(1<<e.bit) != 0
+	e.bit++
+
+	return value
+}
+
+func (e *decoder) table() amqp.Table {
+	inner := &decoder{data: e.take(int(e.long()))}
+	table := amqp.Table{}
+
+	for len(inner.data) > 0 && inner.err == nil {
+		key := inner.shortstr()
+		table[key] = inner.field()
+	}
+	if inner.err != nil && e.err == nil {
+		e.err = inner.err
+	}
+
+	return table
+}
+
+func (e *decoder) array() []interface{} {
+	inner := &decoder{data: e.take(int(e.long()))}
+	array := []interface{}{}
+
+	for len(inner.data) > 0 && inner.err == nil {
+		array = append(array, inner.field())
+	}
+	if inner.err != nil && e.err == nil {
+		e.err = inner.err
+	}
+
+	return array
+}
+
+// field reads a typed value of a table or an array.
+func (e *decoder) field() interface{} {
+	switch kind := e.octet(); kind {
+	case 't':
+		return e.octet() != 0
+	case 'B':
+		return e.octet()
+	case 'b':
+		return int8(e.octet())
+	case 's':
+		return int16(e.short())
+	case 'I':
+		return int32(e.long())
+	case 'l':
+		return int64(e.longlong())
+	case 'f':
+		return math.Float32frombits(e.long())
+	case 'd':
+		return math.Float64frombits(e.longlong())
+	default:
+		return e.composite(kind)
+	}
+}
+
+// composite reads a typed value which is not a number.
+func (e *decoder) composite(kind byte) interface{} {
+	switch kind {
+	case 'D':
+		return amqp.Decimal{Scale: e.octet(), Value: int32(e.long())}
+	case 'S':
+		return e.longstr()
+	case 'A':
+		return e.array()
+	case 'T':
+		return e.timestamp()
+	case 'F':
+		return e.table()
+	case 'x':
+		return append([]byte(nil), e.take(int(e.long()))...)
+	case 'V':
+		return nil
+	default:
+		if e.err == nil {
+			e.err = ErrMalformedFrame
+		}
+		return nil
+	}
+}
+
+// encoder writes AMQP 0-9-1 data types to a frame payload.
+// Consecutive bits are packed in octets.
+type encoder struct {
+	data []byte
+	bit  uint
+}
+
+func (e *encoder) octet(value byte) {
+	e.bit = 0
+	e.data = append(e.data, value)
+}
+
+func (e *encoder) short(value uint16) {
+	e.bit = 0
+	e.data = binary.BigEndian.AppendUint16(e.data, value)
+}
+
+func (e *encoder) long(value uint32) {
+	e.bit = 0
+	e.data = binary.BigEndian.AppendUint32(e.data, value)
+}
+
+func (e *encoder) longlong(value uint64) {
+	e.bit = 0
+	e.data = binary.BigEndian.AppendUint64(e.data, value)
+}
+
+func (e *encoder) shortstr(value string) {
+	if len(value) > math.MaxUint8 {
+		value = value[:math.MaxUint8]
+	}
+	e.octet(byte(len(value)))
+	e.data = append(e.data, value...)
+}
+
+func (e *encoder) longstr(value string) {
+	e.long(uint32(len(value)))
+	e.data = append(e.data, value...)
+}
+
+func (e *encoder) timestamp(value time.Time) {
+	e.longlong(uint64(value.Unix()))
+}
+
+func (e *encoder) boolean(value bool) {
+	if e.bit == 0 || e.bit == 8 {
+		e.data = append(e.data, 0)
+		e.bit = 0
+	}
+	if value {
+		e.data[len(e.data)-1] |= 1 << e.bit
+	}
+	e.bit++
+}
+
+func (e *encoder) table(table amqp.Table) {
+	inner := &encoder{}
+	for key, value := range table {
+		inner.shortstr(key)
+		inner.field(value)
+	}
+
+	e.long(uint32(len(inner.data)))
+	e.data = append(e.data, inner.data...)
+}
+
+func (e *encoder) array(array []interface{}) {
+	inner := &encoder{}
+	for _, value := range array {
+		inner.field(value)
+	}
+
+	e.long(uint32(len(inner.data)))
+	e.data = append(e.data, inner.data...)
+}
+
+// field writes a typed value of a table or an array.
+func (e *encoder) field(value interface{}) {
+	switch value := value.(type) {
+	case bool:
+		e.octet('t')
+		e.boolean(value)
+	case byte:
+		e.octet('B')
+		e.octet(value)
+	case int8:
+		e.octet('b')
+		e.octet(byte(value))
+	case int16:
+		e.octet('s')
+		e.short(uint16(value))
+	case int:
+		e.octet('I')
+		e.long(uint32(value))
+	case int32:
+		e.octet('I')
+		e.long(uint32(value))
+	case int64:
+		e.octet('l')
+		e.longlong(uint64(value))
+	default:
+		e.composite(value)
+	}
+}
+
+// composite writes a typed value which is not an integer.
+// Unsupported types are written as void values.
+func (e *encoder) composite(value interface{}) {
+	switch value := value.(type) {
+	case float32:
+		e.octet('f')
+		e.long(math.Float32bits(value))
+	case float64:
+		e.octet('d')
+		e.longlong(math.Float64bits(value))
+	case amqp.Decimal:
+		e.octet('D')
+		e.octet(value.Scale)
+		e.long(uint32(value.Value))
+	case string:
+		e.octet('S')
+		e.longstr(value)
+	case []interface{}:
+		e.octet('A')
+		e.array(value)
+	case time.Time:
+		e.octet('T')
+		e.timestamp(value)
+	case amqp.Table:
+		e.octet('F')
+		e.table(value)
+	case []byte:
+		e.octet('x')
+		e.long(uint32(len(value)))
+		e.data = append(e.data, value...)
+	default:
+		e.octet('V')
+	}
+}
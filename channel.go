@@ -0,0 +1,125 @@
+package amqpx
+
+import (
+	"context"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Channel describes an amqp channel.
+// Its methods have the same semantics and signatures as the ones of *amqp.Channel from
+// https://github.com/rabbitmq/amqp091-go, which implements this interface.
+type Channel interface {
+	// Close closes the channel.
+	Close() error
+
+	// IsClosed returns if the channel is closed.
+	IsClosed() bool
+
+	// NotifyClose registers a listener for when the channel is closed.
+	NotifyClose(receiver chan *AMQPError) chan *AMQPError
+
+	// NotifyPublish registers a listener for publishing confirmations.
+	NotifyPublish(confirm chan Confirmation) chan Confirmation
+
+	// NotifyReturn registers a listener for messages returned by the broker.
+	NotifyReturn(returns chan Return) chan Return
+
+	// NotifyCancel registers a listener for consumers canceled by the broker.
+	NotifyCancel(cancellations chan string) chan string
+
+	// Qos controls how many messages or bytes the broker will deliver before receiving acknowledgements.
+	Qos(prefetchCount, prefetchSize int, global bool) error
+
+	// Confirm puts the channel into confirm mode.
+	Confirm(noWait bool) error
+
+	// ExchangeDeclare declares an exchange on the broker.
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args Table) error
+
+	// ExchangeDeclarePassive checks if an exchange exists on the broker.
+	ExchangeDeclarePassive(name, kind string, durable, autoDelete, internal, noWait bool, args Table) error
+
+	// ExchangeDelete removes an exchange from the broker.
+	ExchangeDelete(name string, ifUnused, noWait bool) error
+
+	// ExchangeBind binds an exchange to another exchange.
+	ExchangeBind(destination, key, source string, noWait bool, args Table) error
+
+	// ExchangeUnbind unbinds an exchange from another exchange.
+	ExchangeUnbind(destination, key, source string, noWait bool, args Table) error
+
+	// QueueDeclare declares a queue on the broker.
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args Table) (Queue, error)
+
+	// QueueDeclarePassive checks if a queue exists on the broker.
+	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args Table) (Queue, error)
+
+	// QueueBind binds a queue to an exchange.
+	QueueBind(name, key, exchange string, noWait bool, args Table) error
+
+	// QueueUnbind unbinds a queue from an exchange.
+	QueueUnbind(name, key, exchange string, args Table) error
+
+	// QueuePurge removes all messages from a queue which are not waiting for an acknowledgement.
+	QueuePurge(name string, noWait bool) (int, error)
+
+	// QueueDelete removes a queue from the broker.
+	QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error)
+
+	// Consume starts delivering messages from a queue.
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args Table) (<-chan Delivery, error)
+
+	// Cancel stops deliveries to a consumer.
+	Cancel(consumer string, noWait bool) error
+
+	// Get synchronously receives a single message from a queue.
+	Get(queue string, autoAck bool) (Delivery, bool, error)
+
+	// Publish sends a message to an exchange.
+	Publish(exchange, key string, mandatory, immediate bool, msg Publishing) error
+
+	// PublishWithContext sends a message to an exchange.
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg Publishing) error
+
+	// Ack acknowledges a delivery.
+	Ack(tag uint64, multiple bool) error
+
+	// Nack negatively acknowledges a delivery.
+	Nack(tag uint64, multiple bool, requeue bool) error
+
+	// Reject negatively acknowledges a delivery.
+	Reject(tag uint64, requeue bool) error
+}
+
+// UnwrapChannel returns the *amqp.Channel used by given Channel, if any.
+// It allows to call methods of *amqp.Channel which are not exposed by Channel interface.
+func UnwrapChannel(channel Channel) (*amqp.Channel, bool) {
+	for {
+		switch value := channel.(type) {
+		case *amqp.Channel:
+			return value, true
+		case interface{ Unwrap() Channel }:
+			channel = value.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// trackChannel counts given channel in given counter until it's closed.
+func trackChannel(channel Channel, channels *atomic.Int64) {
+	channels.Add(1)
+
+	receiver := channel.NotifyClose(make(chan *AMQPError, 1))
+	go func() {
+		// The receiver is closed once the channel is closed, after an optional error.
+		for range receiver {
+			continue
+		}
+		channels.Add(-1)
+	}()
+}
+
+var _ Channel = (*amqp.Channel)(nil)
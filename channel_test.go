@@ -0,0 +1,32 @@
+package amqpx_test
+
+import (
+	"testing"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestUnwrapChannel(t *testing.T) {
+	is := NewRunner(t)
+
+	client, err := NewClient()
+	is.NoError(err)
+	is.NotNil(client)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	is.NotNil(channel)
+
+	raw, ok := amqpx.UnwrapChannel(channel)
+	is.True(ok)
+	is.NotNil(raw)
+	is.NoError(raw.Tx())
+	is.NoError(channel.Close())
+
+	raw, ok = amqpx.UnwrapChannel(nil)
+	is.False(ok)
+	is.Nil(raw)
+}
@@ -1,32 +1,56 @@
 package amqpx
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
 )
 
 // Client interface describes a amqp client.
 type Client interface {
 	// Channel returns a new Channel from current client unless it's closed.
-	Channel() (*amqp.Channel, error)
+	Channel() (Channel, error)
 
-	// Close closes the client.
+	// Close closes the client. Closing it again is a no-op.
 	Close() error
 
+	// Shutdown gracefully closes the client: it stops handing out channels, and waits for the borrowed ones to be
+	// closed until given context is done, before closing the client.
+	Shutdown(ctx context.Context) error
+
 	// IsClosed returns if the client is closed.
 	IsClosed() bool
 }
 
 // New returns a new Client with the given Dialer and options.
 func New(dialer Dialer, options ...ClientOption) (Client, error) {
+	opts, err := newClientOptions(dialer, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.usePool {
+		opts.logger.Debug("Connection pooling is disabled")
+		return newSimple(opts)
+	}
+
+	opts.logger.Debug("Connection pooling is enabled", Field{Key: "capacity", Value: opts.capacity})
+
+	return newPool(opts)
+}
+
+// newClientOptions returns the options of a client with the given Dialer, once given options are applied.
+func newClientOptions(dialer Dialer, options []ClientOption) (*clientOptions, error) {
 	opts := &clientOptions{
 		dialer:   dialer,
 		observer: &defaultObserver{},
-		logger:   &noopLogger{},
+		logger:   noopLogger{},
+		metrics:  &noopMetrics{},
 		usePool:  true,
 		capacity: DefaultConnectionsCapacity,
+		parallel: DefaultStartupParallelism,
+		drain:    DefaultDrainTimeout,
+		backoff:  backoff{min: DefaultReconnectBackoffMin, max: DefaultReconnectBackoffMax},
 	}
 
 	for _, option := range options {
@@ -36,12 +60,9 @@ func New(dialer Dialer, options ...ClientOption) (Client, error) {
 		}
 	}
 
-	if !opts.usePool {
-		opts.logger.Debug("Connection pooling is disabled")
-		return newSimple(opts)
+	if opts.dedup > 0 {
+		opts.logger = NewDedupLogger(opts.logger, opts.dedup)
 	}
 
-	opts.logger.Debug(fmt.Sprintf("Connection pooling is enabled (%d connections)", opts.capacity))
-
-	return newPool(opts)
+	return opts, nil
 }
@@ -1,5 +1,9 @@
 package amqpx
 
+import (
+	"time"
+)
+
 // ClientOption is used to define Client options.
 type ClientOption interface {
 	apply(*clientOptions) error
@@ -14,9 +18,22 @@ func (o clientOption) apply(instance *clientOptions) error {
 type clientOptions struct {
 	dialer   Dialer
 	observer Observer
-	logger   Logger
+	logger   StructuredLogger
+	metrics  Metrics
+	dedup    time.Duration
 	usePool  bool
 	capacity int
+	strategy SelectionStrategy
+	backoff  backoff
+	minimum  int
+	lazy     bool
+	parallel int
+	deadline time.Duration
+	drain    time.Duration
+	lifetime time.Duration
+	jitter   time.Duration
+
+	autoscaling *AutoscalingPolicy
 }
 
 // WithCapacity will configure a Client with the given number of connections.
@@ -31,6 +48,118 @@ func WithCapacity(capacity int) ClientOption {
 	})
 }
 
+// WithMinConnections will configure a Client to start once the given number of connections are opened,
+// instead of all of them. The remaining connections are opened in the background.
+func WithMinConnections(minimum int) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if minimum <= 0 {
+			return ErrInvalidMinConnections
+		}
+		options.minimum = minimum
+		options.lazy = false
+		return nil
+	})
+}
+
+// WithLazyConnections will configure a Client to start without waiting for any connection:
+// all of them are opened in the background.
+func WithLazyConnections() ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		options.minimum = 0
+		options.lazy = true
+		return nil
+	})
+}
+
+// WithStartupParallelism will configure how many connections a Client dials concurrently when it's created.
+func WithStartupParallelism(parallelism int) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if parallelism <= 0 {
+			return ErrInvalidStartupParallelism
+		}
+		options.parallel = parallelism
+		return nil
+	})
+}
+
+// WithStartupTimeout will configure the overall time a Client waits for its connections when it's created.
+// Once it's reached, the Client starts if the minimum number of connections is opened, and opens the remaining ones
+// in the background. Otherwise, it fails with ErrStartupTimeout.
+func WithStartupTimeout(timeout time.Duration) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if timeout <= 0 {
+			return ErrInvalidStartupTimeout
+		}
+		options.deadline = timeout
+		return nil
+	})
+}
+
+// WithDrainTimeout will configure how long a connection removed by a resize waits for its channels to be closed,
+// before it's closed anyway.
+func WithDrainTimeout(timeout time.Duration) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if timeout <= 0 {
+			return ErrInvalidDrainTimeout
+		}
+		options.drain = timeout
+		return nil
+	})
+}
+
+// WithConnectionMaxLifetime will configure a Client to replace each connection of its pool once it has been open for
+// the given lifetime, minus a random jitter so connections are not all replaced at once.
+// The new connection is opened first, then the old one is drained like with a resize.
+func WithConnectionMaxLifetime(lifetime time.Duration, jitter time.Duration) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if lifetime <= 0 || jitter < 0 || jitter >= lifetime {
+			return ErrInvalidConnectionMaxLifetime
+		}
+		options.lifetime = lifetime
+		options.jitter = jitter
+		return nil
+	})
+}
+
+// WithAutoscaling will configure a Client to resize its connections pool according to the given policy.
+// The initial capacity is kept within the policy bounds.
+func WithAutoscaling(policy AutoscalingPolicy) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		err := policy.validate()
+		if err != nil {
+			return err
+		}
+		if policy.Interval == 0 {
+			policy.Interval = DefaultAutoscalingInterval
+		}
+		options.autoscaling = &policy
+		return nil
+	})
+}
+
+// WithSelectionStrategy will configure how a Client picks a connection of its pool when a channel is requested.
+func WithSelectionStrategy(strategy SelectionStrategy) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if strategy > SelectionFirstAvailable {
+			return ErrInvalidSelectionStrategy
+		}
+		options.strategy = strategy
+		return nil
+	})
+}
+
+// WithReconnectBackoff will configure how long the connections pool waits between two attempts to replace a lost
+// connection: a random delay between the given minimum and maximum.
+func WithReconnectBackoff(min time.Duration, max time.Duration) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if min <= 0 || max < min {
+			return ErrInvalidReconnectBackoff
+		}
+		options.backoff = backoff{min: min, max: max}
+		return nil
+	})
+}
+
 // WithoutConnectionsPool will configure a Client without a connections pool.
 func WithoutConnectionsPool() ClientOption {
 	return clientOption(func(options *clientOptions) error {
@@ -52,6 +181,17 @@ func WithObserver(observer Observer) ClientOption {
 
 // WithLogger will configure Client with the given Logger.
 func WithLogger(logger Logger) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if logger == nil {
+			return ErrLoggerRequired
+		}
+		options.logger = NewLoggerShim(logger)
+		return nil
+	})
+}
+
+// WithStructuredLogger will configure Client with the given StructuredLogger.
+func WithStructuredLogger(logger StructuredLogger) ClientOption {
 	return clientOption(func(options *clientOptions) error {
 		if logger == nil {
 			return ErrLoggerRequired
@@ -68,3 +208,27 @@ func WithLoggerLevel(level LoggerLevel) ClientOption {
 		return nil
 	})
 }
+
+// WithMetrics will configure Client with the given metrics collector.
+func WithMetrics(metrics Metrics) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if metrics == nil {
+			return ErrMetricsRequired
+		}
+		options.metrics = metrics
+		return nil
+	})
+}
+
+// WithLoggerDeduplication will configure Client to suppress similar log messages within the given window,
+// such as the same warning emitted for every connection of a pool during an outage.
+// A summary with the number of suppressed messages is emitted at the end of the window.
+func WithLoggerDeduplication(window time.Duration) ClientOption {
+	return clientOption(func(options *clientOptions) error {
+		if window <= 0 {
+			return ErrInvalidLoggerDeduplicationWindow
+		}
+		options.dedup = window
+		return nil
+	})
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
-	"github.com/ulule/amqpx"
+	"github.com/ulule/amqpx/v3"
 )
 
 var (
@@ -114,6 +114,19 @@ func (e *Runner) Contains(s interface{}, contains interface{}, msgAndArgs ...int
 	e.require.Contains(s, contains, msgAndArgs...)
 }
 
+func (e *Runner) Eventually(condition func() bool, waitFor time.Duration, tick time.Duration,
+	msgAndArgs ...interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.require.Eventually(condition, waitFor, tick, msgAndArgs...)
+}
+
+func (e *Runner) Fail(failureMessage string, msgAndArgs ...interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.require.Fail(failureMessage, msgAndArgs...)
+}
+
 func TestClusterMode(t *testing.T) {
 	if !IsClusterMode() {
 		t.Skip()
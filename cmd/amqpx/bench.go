@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+// strategies are the selection strategies of a connections pool, by name.
+var strategies = map[string]amqpx.SelectionStrategy{
+	"random":      amqpx.SelectionRandom,
+	"round-robin": amqpx.SelectionRoundRobin,
+	"first":       amqpx.SelectionFirstAvailable,
+}
+
+// benchFlags are the flags of the bench command.
+type benchFlags struct {
+	config     benchConfig
+	capacities listFlag
+	strategies listFlag
+	local      bool
+	json       bool
+}
+
+// runBench runs a workload for each pool capacity and selection strategy, and reports their results.
+func runBench(ctx context.Context, args []string, streams streams) error {
+	set, client := newFlagSet("bench", streams)
+	flags := benchFlags{}
+	set.IntVar(&flags.config.publishers, "publishers", 4, "Number of publishers")
+	set.IntVar(&flags.config.consumers, "consumers", 4, "Number of consumers")
+	set.DurationVar(&flags.config.duration, "duration", 10*time.Second, "Duration of each run")
+	set.IntVar(&flags.config.size, "size", 256, "Size of messages, in bytes")
+	set.IntVar(&flags.config.prefetch, "prefetch", 50, "Number of unacknowledged messages per consumer")
+	set.BoolVar(&flags.config.confirm, "confirm", true, "Wait for the confirmation of each message")
+	set.BoolVar(&flags.config.reconnect, "reconnect", false, "Drop every connection halfway through each run")
+	set.Var(&flags.capacities, "capacities", "Pool capacities to compare, comma-separated (default 1,4,16)")
+	set.Var(&flags.strategies, "strategies", "Selection strategies to compare: random, round-robin or first "+
+		"(default random)")
+	set.BoolVar(&flags.local, "local", false, "Run against an in-process stand-in broker, instead of -uri")
+	set.BoolVar(&flags.json, "json", false, "Print results as JSON lines")
+
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	capacities, selections, err := flags.matrix()
+	if err != nil {
+		return err
+	}
+
+	if flags.local {
+		server, err := amqpxtest.NewServer(amqpxtest.NewBroker())
+		if err != nil {
+			return err
+		}
+		defer func() {
+			thr := server.Close()
+			_ = thr
+		}()
+		client.uris = listFlag{server.URI()}
+	}
+
+	results := []benchResult{}
+	for _, capacity := range capacities {
+		for _, name := range selections {
+			result, err := bench(ctx, client, streams, flags.config, capacity, name)
+			if err != nil {
+				return errors.Wrapf(err, "cannot run benchmark with capacity %d and strategy %s", capacity, name)
+			}
+			results = append(results, result)
+		}
+	}
+
+	if flags.json {
+		return printJSON(streams, results)
+	}
+
+	return printTable(streams, results)
+}
+
+// matrix returns the pool capacities and the selection strategies to compare.
+func (e benchFlags) matrix() ([]int, []string, error) {
+	if len(e.capacities) == 0 {
+		e.capacities = listFlag{"1", "4", "16"}
+	}
+	if len(e.strategies) == 0 {
+		e.strategies = listFlag{"random"}
+	}
+
+	capacities := []int{}
+	for _, value := range e.capacities {
+		capacity, err := strconv.Atoi(value)
+		if err != nil || capacity <= 0 {
+			return nil, nil, errors.Wrapf(amqpx.ErrInvalidConnectionsPoolCapacity, "%q", value)
+		}
+		capacities = append(capacities, capacity)
+	}
+
+	for _, name := range e.strategies {
+		_, ok := strategies[name]
+		if !ok {
+			return nil, nil, errors.Wrapf(amqpx.ErrInvalidSelectionStrategy, "%q", name)
+		}
+	}
+
+	return capacities, e.strategies, nil
+}
+
+// bench runs the workload with a new client of given capacity and selection strategy.
+func bench(ctx context.Context, flags *clientFlags, streams streams, config benchConfig, capacity int,
+	strategy string) (benchResult, error) {
+
+	dialer, err := flags.dialer()
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	faults, err := amqpx.NewFaultDialer(dialer)
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	options, err := flags.options(streams)
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	metrics := &benchMetrics{}
+	client, err := amqpx.New(faults, append(options,
+		amqpx.WithCapacity(capacity),
+		amqpx.WithSelectionStrategy(strategies[strategy]),
+		amqpx.WithMetrics(metrics),
+	)...)
+	if err != nil {
+		return benchResult{}, err
+	}
+	defer func() {
+		thr := client.Close()
+		_ = thr
+	}()
+
+	result, err := newBenchRun(client, faults, metrics, config).run(ctx)
+	result.Capacity = capacity
+	result.Strategy = strategy
+
+	return result, err
+}
+
+// printJSON prints given results as JSON lines.
+func printJSON(streams streams, results []benchResult) error {
+	encoder := json.NewEncoder(streams.stdout)
+	for _, result := range results {
+		err := encoder.Encode(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printTable prints given results as a table.
+func printTable(streams streams, results []benchResult) error {
+	writer := tabwriter.NewWriter(streams.stdout, 0, 0, 2, ' ', 0)
+	printf(writer, "CAPACITY\tSTRATEGY\tPUBLISH/S\tCONSUME/S\tLATENCY P50/P90/P99/MAX\t"+
+		"CONFIRM P50/P99\tCHANNEL P50/P99\tERRORS\tRECONNECTS\tRECOVERY\n")
+
+	for _, result := range results {
+		printf(writer, "%d\t%s\t%.0f\t%.0f\t%s/%s/%s/%s\t%s/%s\t%s/%s\t%d\t%d\t%s\n",
+			result.Capacity, result.Strategy, result.PublishRate, result.ConsumeRate,
+			round(result.Latency.P50), round(result.Latency.P90), round(result.Latency.P99), round(result.Latency.Max),
+			round(result.ConfirmLatency.P50), round(result.ConfirmLatency.P99),
+			round(result.ChannelLatency.P50), round(result.ChannelLatency.P99),
+			result.Errors, result.Reconnects, round(result.ReconnectRecovery))
+	}
+
+	return writer.Flush()
+}
+
+// round rounds given duration for display.
+func round(duration time.Duration) string {
+	return fmt.Sprint(duration.Round(10 * time.Microsecond))
+}
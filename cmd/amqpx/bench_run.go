@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// benchSentHeader is the header holding the time a benchmark message was published, in nanoseconds.
+const benchSentHeader = "x-bench-sent"
+
+// benchRetryDelay is the delay before a publisher or a consumer opens a new channel after a failure.
+const benchRetryDelay = 50 * time.Millisecond
+
+// benchConfig is the workload of a benchmark.
+type benchConfig struct {
+	publishers int
+	consumers  int
+	duration   time.Duration
+	size       int
+	prefetch   int
+	confirm    bool
+	reconnect  bool
+}
+
+// benchResult is the report of a benchmark run, for a pool capacity and a selection strategy.
+type benchResult struct {
+	Capacity          int           `json:"capacity"`
+	Strategy          string        `json:"strategy"`
+	Duration          time.Duration `json:"duration"`
+	Published         int64         `json:"published"`
+	Consumed          int64         `json:"consumed"`
+	PublishRate       float64       `json:"publish_rate"`
+	ConsumeRate       float64       `json:"consume_rate"`
+	Latency           quantiles     `json:"latency"`
+	ConfirmLatency    quantiles     `json:"confirm_latency"`
+	ChannelLatency    quantiles     `json:"channel_latency"`
+	Errors            int64         `json:"errors"`
+	Reconnects        int64         `json:"reconnects"`
+	ReconnectRecovery time.Duration `json:"reconnect_recovery"`
+}
+
+// benchMetrics counts the reconnections of a benchmark client.
+type benchMetrics struct {
+	reconnects atomic.Int64
+}
+
+func (e *benchMetrics) OnDial(duration time.Duration, err error) {}
+func (e *benchMetrics) OnConnectionOpen()                        {}
+func (e *benchMetrics) OnConnectionClose()                       {}
+func (e *benchMetrics) OnReconnect()                             { e.reconnects.Add(1) }
+func (e *benchMetrics) OnConnectionWait(duration time.Duration)  {}
+func (e *benchMetrics) OnChannelOpen(err error)                  {}
+
+var _ amqpx.Metrics = (*benchMetrics)(nil)
+
+// benchWorker publishes or consumes messages on a channel opened at given time.
+type benchWorker func(ctx context.Context, channel amqpx.Channel, opened time.Time)
+
+// benchRun drives publishers and consumers through a client, on a dedicated queue.
+type benchRun struct {
+	client    amqpx.Client
+	dialer    *amqpx.FaultDialer
+	metrics   *benchMetrics
+	config    benchConfig
+	queue     string
+	body      []byte
+	published atomic.Int64
+	consumed  atomic.Int64
+	errors    atomic.Int64
+	dropped   atomic.Int64
+	recovery  atomic.Int64
+	latency   samples
+	confirms  samples
+	channels  samples
+}
+
+// newBenchRun returns a new benchRun for given client.
+func newBenchRun(client amqpx.Client, dialer *amqpx.FaultDialer, metrics *benchMetrics,
+	config benchConfig) *benchRun {
+
+	return &benchRun{
+		client:  client,
+		dialer:  dialer,
+		metrics: metrics,
+		config:  config,
+		queue:   fmt.Sprintf("amqpx.bench.%d", time.Now().UnixNano()),
+		body:    make([]byte, config.size),
+	}
+}
+
+// run runs the workload until the configured duration, or given context is done.
+func (e *benchRun) run(ctx context.Context) (benchResult, error) {
+	err := e.setup(func(channel amqpx.Channel) error {
+		_, err := channel.QueueDeclare(e.queue, false, false, false, false, nil)
+		return err
+	})
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.config.duration)
+	defer cancel()
+
+	if e.config.reconnect {
+		timer := time.AfterFunc(e.config.duration/2, e.drop)
+		defer timer.Stop()
+	}
+
+	start := time.Now()
+	group := sync.WaitGroup{}
+	for i := 0; i < e.config.consumers; i++ {
+		group.Add(1)
+		go e.loop(ctx, &group, e.consume)
+	}
+	for i := 0; i < e.config.publishers; i++ {
+		group.Add(1)
+		go e.loop(ctx, &group, e.publish)
+	}
+	group.Wait()
+	elapsed := time.Since(start)
+
+	err = e.setup(func(channel amqpx.Channel) error {
+		_, err := channel.QueueDelete(e.queue, false, false, false)
+		return err
+	})
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	return e.result(elapsed), nil
+}
+
+// setup runs given function on a new channel. Since connections may be reconnecting, channels are retried
+// until the dialer timeout.
+func (e *benchRun) setup(fn func(channel amqpx.Channel) error) error {
+	deadline := time.Now().Add(e.dialer.Timeout())
+	for {
+		channel, err := e.client.Channel()
+		if err == nil {
+			err = fn(channel)
+			e.release(channel)
+			return err
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(benchRetryDelay)
+	}
+}
+
+// result returns the report of the run.
+func (e *benchRun) result(elapsed time.Duration) benchResult {
+	return benchResult{
+		Duration:          elapsed,
+		Published:         e.published.Load(),
+		Consumed:          e.consumed.Load(),
+		PublishRate:       float64(e.published.Load()) / elapsed.Seconds(),
+		ConsumeRate:       float64(e.consumed.Load()) / elapsed.Seconds(),
+		Latency:           e.latency.quantiles(),
+		ConfirmLatency:    e.confirms.quantiles(),
+		ChannelLatency:    e.channels.quantiles(),
+		Errors:            e.errors.Load(),
+		Reconnects:        e.metrics.reconnects.Load(),
+		ReconnectRecovery: time.Duration(e.recovery.Load()),
+	}
+}
+
+// loop runs given worker on new channels, until given context is done.
+func (e *benchRun) loop(ctx context.Context, group *sync.WaitGroup, worker benchWorker) {
+	defer group.Done()
+
+	for ctx.Err() == nil {
+		opened := time.Now()
+		channel, err := e.channel()
+		if err != nil {
+			time.Sleep(benchRetryDelay)
+			continue
+		}
+
+		worker(ctx, channel, opened)
+		e.release(channel)
+	}
+}
+
+// channel opens a new channel, and records how long it took.
+func (e *benchRun) channel() (amqpx.Channel, error) {
+	start := time.Now()
+
+	channel, err := e.client.Channel()
+	if err != nil {
+		e.errors.Add(1)
+		return nil, err
+	}
+
+	e.channels.add(time.Since(start))
+
+	return channel, nil
+}
+
+// release closes given channel.
+func (e *benchRun) release(channel amqpx.Channel) {
+	thr := channel.Close()
+	_ = thr
+}
+
+// drop drops every connection of the client, to measure the impact of a reconnection.
+func (e *benchRun) drop() {
+	e.dropped.Store(time.Now().UnixNano())
+	e.dialer.DropConnections()
+}
+
+// recovered records the time between the drop of connections and the first message published afterwards,
+// on a channel opened after given time.
+func (e *benchRun) recovered(opened time.Time) {
+	dropped := e.dropped.Load()
+	if dropped == 0 || opened.UnixNano() < dropped || e.recovery.Load() != 0 {
+		return
+	}
+
+	e.recovery.CompareAndSwap(0, time.Now().UnixNano()-dropped)
+}
+
+// publish publishes messages on given channel until an error occurs or given context is done.
+// With confirms, each message waits for its confirmation before the next one is published.
+func (e *benchRun) publish(ctx context.Context, channel amqpx.Channel, opened time.Time) {
+	var confirms chan amqpx.Confirmation
+	if e.config.confirm {
+		err := channel.Confirm(false)
+		if err != nil {
+			e.errors.Add(1)
+			return
+		}
+		confirms = channel.NotifyPublish(make(chan amqpx.Confirmation, 1))
+	}
+
+	for ctx.Err() == nil {
+		sent := time.Now()
+		err := channel.Publish("", e.queue, false, false, amqpx.Publishing{
+			Headers: amqpx.Table{benchSentHeader: sent.UnixNano()},
+			Body:    e.body,
+		})
+		if err != nil {
+			e.errors.Add(1)
+			return
+		}
+
+		if confirms != nil && !e.confirm(ctx, confirms, sent) {
+			return
+		}
+
+		e.published.Add(1)
+		e.recovered(opened)
+	}
+}
+
+// confirm waits for the confirmation of a message published at given time, and returns if it was received.
+func (e *benchRun) confirm(ctx context.Context, confirms chan amqpx.Confirmation, sent time.Time) bool {
+	select {
+	case confirmation, ok := <-confirms:
+		if !ok || !confirmation.Ack {
+			e.errors.Add(1)
+			return false
+		}
+		e.confirms.add(time.Since(sent))
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// consume consumes messages on given channel until it's closed or given context is done.
+func (e *benchRun) consume(ctx context.Context, channel amqpx.Channel, opened time.Time) {
+	err := channel.Qos(e.config.prefetch, 0, false)
+	if err != nil {
+		e.errors.Add(1)
+		return
+	}
+
+	deliveries, err := channel.Consume(e.queue, "", false, false, false, false, nil)
+	if err != nil {
+		e.errors.Add(1)
+		return
+	}
+
+	for {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return
+			}
+
+			sent, ok := delivery.Headers[benchSentHeader].(int64)
+			if ok {
+				e.latency.add(time.Since(time.Unix(0, sent)))
+			}
+
+			err = delivery.Ack(false)
+			if err != nil {
+				e.errors.Add(1)
+				return
+			}
+			e.consumed.Add(1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
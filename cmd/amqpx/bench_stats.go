@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// samples collects durations, to compute their percentiles.
+type samples struct {
+	mutex  sync.Mutex
+	values []time.Duration
+}
+
+// add records given duration.
+func (e *samples) add(duration time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.values = append(e.values, duration)
+}
+
+// percentiles returns the durations at given percentiles, from 0 to 100.
+func (e *samples) percentiles(percentiles ...float64) []time.Duration {
+	e.mutex.Lock()
+	values := append([]time.Duration{}, e.values...)
+	e.mutex.Unlock()
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	result := make([]time.Duration, len(percentiles))
+	if len(values) == 0 {
+		return result
+	}
+
+	for i, percentile := range percentiles {
+		rank := int(percentile / 100 * float64(len(values)-1))
+		result[i] = values[rank]
+	}
+
+	return result
+}
+
+// quantiles are the percentiles of durations reported by a benchmark.
+type quantiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// quantiles returns the usual percentiles of the durations.
+func (e *samples) quantiles() quantiles {
+	values := e.percentiles(50, 90, 99, 100)
+
+	return quantiles{
+		P50: values[0],
+		P90: values[1],
+		P99: values[2],
+		Max: values[3],
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Bench(t *testing.T) {
+	is := require.New(t)
+
+	code, stdout, stderr := Run(t, "", "bench", "-local", "-duration", "500ms", "-publishers", "2",
+		"-consumers", "2", "-capacities", "1,2", "-strategies", "random,first", "-reconnect", "-json")
+	is.Equal(0, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	is.Len(lines, 4)
+
+	for i, line := range lines {
+		result := benchResult{}
+		is.NoError(json.Unmarshal([]byte(line), &result))
+		is.Equal([]int{1, 1, 2, 2}[i], result.Capacity)
+		is.Equal([]string{"random", "first", "random", "first"}[i], result.Strategy)
+		is.Positive(result.Published)
+		is.Positive(result.Consumed)
+		is.Positive(result.PublishRate)
+		is.Positive(result.Latency.P50)
+		is.Positive(result.ConfirmLatency.P50)
+		is.Positive(result.ChannelLatency.P50)
+		is.GreaterOrEqual(result.Reconnects, int64(result.Capacity))
+		is.Positive(result.ReconnectRecovery)
+	}
+
+	code, stdout, stderr = Run(t, "", "bench", "-local", "-duration", "200ms", "-capacities", "1")
+	is.Equal(0, code, stderr)
+	is.True(strings.HasPrefix(stdout, "CAPACITY"))
+	is.Contains(stdout, "random")
+
+	code, _, stderr = Run(t, "", "bench", "-local", "-strategies", "fastest")
+	is.Equal(1, code)
+	is.Contains(stderr, "invalid connections pool selection strategy")
+
+	code, _, stderr = Run(t, "", "bench", "-local", "-capacities", "0")
+	is.Equal(1, code)
+	is.Contains(stderr, "invalid connections pool capacity")
+}
+
+func TestSamples_Percentiles(t *testing.T) {
+	is := require.New(t)
+
+	values := &samples{}
+	is.Equal(quantiles{}, values.quantiles())
+
+	for i := 100; i > 0; i-- {
+		values.add(time.Duration(i) * time.Millisecond)
+	}
+
+	is.Equal(quantiles{
+		P50: 50 * time.Millisecond,
+		P90: 90 * time.Millisecond,
+		P99: 99 * time.Millisecond,
+		Max: 100 * time.Millisecond,
+	}, values.quantiles())
+}
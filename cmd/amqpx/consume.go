@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// record is the JSON representation of a delivery printed by the consume command.
+type record struct {
+	Exchange      string      `json:"exchange"`
+	RoutingKey    string      `json:"routing_key"`
+	DeliveryTag   uint64      `json:"delivery_tag"`
+	Redelivered   bool        `json:"redelivered"`
+	Headers       amqpx.Table `json:"headers,omitempty"`
+	ContentType   string      `json:"content_type,omitempty"`
+	MessageID     string      `json:"message_id,omitempty"`
+	CorrelationID string      `json:"correlation_id,omitempty"`
+	Timestamp     *time.Time  `json:"timestamp,omitempty"`
+	Body          string      `json:"body"`
+	Encoding      string      `json:"encoding,omitempty"`
+}
+
+// newRecord returns the record of given delivery. Bodies which aren't valid UTF-8 are encoded in base64.
+func newRecord(delivery amqpx.Delivery) record {
+	value := record{
+		Exchange:      delivery.Exchange,
+		RoutingKey:    delivery.RoutingKey,
+		DeliveryTag:   delivery.DeliveryTag,
+		Redelivered:   delivery.Redelivered,
+		Headers:       delivery.Headers,
+		ContentType:   delivery.ContentType,
+		MessageID:     delivery.MessageId,
+		CorrelationID: delivery.CorrelationId,
+		Body:          string(delivery.Body),
+	}
+
+	if !delivery.Timestamp.IsZero() {
+		timestamp := delivery.Timestamp.UTC()
+		value.Timestamp = &timestamp
+	}
+
+	if !utf8.Valid(delivery.Body) {
+		value.Body = base64.StdEncoding.EncodeToString(delivery.Body)
+		value.Encoding = "base64"
+	}
+
+	return value
+}
+
+// consumeFlags are the flags of the consume command.
+type consumeFlags struct {
+	queue    string
+	count    int
+	prefetch int
+	requeue  bool
+}
+
+// runConsume prints the messages of a queue as JSON lines, until given count or an interruption.
+func runConsume(ctx context.Context, args []string, streams streams) error {
+	set, client := newFlagSet("consume", streams)
+	flags := consumeFlags{}
+	set.StringVar(&flags.queue, "queue", "", "Queue to consume")
+	set.IntVar(&flags.count, "count", 0, "Number of messages to consume, unlimited if zero")
+	set.IntVar(&flags.prefetch, "prefetch", 10, "Number of unacknowledged messages")
+	set.BoolVar(&flags.requeue, "requeue", false, "Requeue messages instead of acknowledging them")
+
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.queue == "" {
+		return ErrQueueRequired
+	}
+
+	channel, release, err := client.channel(streams)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = channel.Qos(flags.prefetch, 0, false)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := channel.Consume(flags.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	return consume(ctx, deliveries, flags, json.NewEncoder(streams.stdout))
+}
+
+// consume prints given deliveries, then acknowledges or requeues them.
+func consume(ctx context.Context, deliveries <-chan amqpx.Delivery, flags consumeFlags, encoder *json.Encoder) error {
+	for received := 0; flags.count == 0 || received < flags.count; received++ {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return nil
+			}
+
+			err := encoder.Encode(newRecord(delivery))
+			if err != nil {
+				return err
+			}
+
+			if flags.requeue {
+				err = delivery.Nack(false, true)
+			} else {
+				err = delivery.Ack(false)
+			}
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
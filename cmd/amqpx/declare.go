@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// runDeclare declares an exchange, a queue or a binding.
+func runDeclare(ctx context.Context, args []string, streams streams) error {
+	if len(args) == 0 {
+		return ErrInvalidResource
+	}
+
+	switch args[0] {
+	case "exchange":
+		return declareExchange(args[1:], streams)
+	case "queue":
+		return declareQueue(args[1:], streams)
+	case "binding":
+		return declareBinding(args[1:], streams)
+	default:
+		return ErrInvalidResource
+	}
+}
+
+// declareExchange declares an exchange described by given arguments.
+func declareExchange(args []string, streams streams) error {
+	set, client := newFlagSet("declare exchange", streams)
+	spec := exchangeSpec{}
+	set.StringVar(&spec.Name, "name", "", "Exchange name")
+	set.StringVar(&spec.Type, "type", "direct", "Exchange type: direct, fanout, topic or headers")
+	set.BoolVar(&spec.Durable, "durable", true, "Survive a broker restart")
+	set.BoolVar(&spec.AutoDelete, "auto-delete", false, "Delete when the last binding is removed")
+	set.BoolVar(&spec.Internal, "internal", false, "Only accept messages from other exchanges")
+
+	return declare(set, args, client, streams, func(channel amqpx.Channel) error {
+		err := spec.declare(channel)
+		if err != nil {
+			return err
+		}
+
+		printf(streams.stdout, "exchange %s declared\n", spec.Name)
+		return nil
+	})
+}
+
+// declareQueue declares a queue described by given arguments.
+func declareQueue(args []string, streams streams) error {
+	set, client := newFlagSet("declare queue", streams)
+	spec := queueSpec{}
+	set.StringVar(&spec.Name, "name", "", "Queue name")
+	set.BoolVar(&spec.Durable, "durable", true, "Survive a broker restart")
+	set.BoolVar(&spec.AutoDelete, "auto-delete", false, "Delete when the last consumer is cancelled")
+
+	return declare(set, args, client, streams, func(channel amqpx.Channel) error {
+		queue, err := spec.declare(channel)
+		if err != nil {
+			return err
+		}
+
+		printf(streams.stdout, "queue %s declared (%d message(s), %d consumer(s))\n",
+			queue.Name, queue.Messages, queue.Consumers)
+		return nil
+	})
+}
+
+// declareBinding declares a binding described by given arguments.
+func declareBinding(args []string, streams streams) error {
+	set, client := newFlagSet("declare binding", streams)
+	spec := bindingSpec{}
+	set.StringVar(&spec.Source, "source", "", "Source exchange")
+	set.StringVar(&spec.Destination, "destination", "", "Destination queue or exchange")
+	set.StringVar(&spec.DestinationType, "destination-type", destinationQueue, "Destination type: queue or exchange")
+	set.StringVar(&spec.RoutingKey, "key", "", "Routing key")
+
+	return declare(set, args, client, streams, func(channel amqpx.Channel) error {
+		err := spec.declare(channel)
+		if err != nil {
+			return err
+		}
+
+		printf(streams.stdout, "%s %s bound to %s\n", spec.DestinationType, spec.Destination, spec.Source)
+		return nil
+	})
+}
+
+// declare parses given arguments, then runs given function on a new channel.
+func declare(set *flag.FlagSet, args []string, client *clientFlags, streams streams,
+	fn func(channel amqpx.Channel) error) error {
+
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	channel, release, err := client.channel(streams)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(channel)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+)
+
+var (
+	// ErrQueueRequired occurs when a command needs a queue name.
+	ErrQueueRequired = fmt.Errorf("a queue name is required")
+
+	// ErrExchangeRequired occurs when a command needs an exchange name.
+	ErrExchangeRequired = fmt.Errorf("an exchange name is required")
+
+	// ErrBindingRequired occurs when a binding has no source or destination.
+	ErrBindingRequired = fmt.Errorf("a binding source and destination are required")
+
+	// ErrInvalidDestinationType occurs when a binding destination is neither a queue nor an exchange.
+	ErrInvalidDestinationType = fmt.Errorf("invalid binding destination type")
+
+	// ErrInvalidResource occurs when the declare command is given an unknown kind of resource.
+	ErrInvalidResource = fmt.Errorf("expected exchange, queue or binding")
+
+	// ErrInvalidTopologyCommand occurs when the topology command is given an unknown action.
+	ErrInvalidTopologyCommand = fmt.Errorf("expected apply")
+
+	// ErrInvalidHeader occurs when a header flag is not formatted as key=value.
+	ErrInvalidHeader = fmt.Errorf("invalid header, expected key=value")
+
+	// ErrMessageNotConfirmed occurs when the broker rejects a published message.
+	ErrMessageNotConfirmed = fmt.Errorf("message not confirmed by the broker")
+
+	// ErrConfirmationsClosed occurs when the channel is closed before every confirmation is received.
+	ErrConfirmationsClosed = fmt.Errorf("channel closed while waiting for confirmations")
+)
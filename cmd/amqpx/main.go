@@ -0,0 +1,100 @@
+// Command amqpx is a command-line tool to publish, consume and declare resources on RabbitMQ, using the same
+// dialers and client as applications built with amqpx.
+//
+// Usage:
+//
+//	amqpx <command> [flags]
+//
+// Commands are publish, consume, declare, purge, ping, topology and bench. Run "amqpx <command> -h" for their flags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// streams are the standard streams of a command.
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is a subcommand of the tool.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, streams streams) error
+}
+
+// commands returns the subcommands of the tool.
+func commands() []command {
+	return []command{
+		{name: "publish", summary: "Publish messages read from stdin or a file", run: runPublish},
+		{name: "consume", summary: "Consume messages from a queue, printed as JSON lines", run: runConsume},
+		{name: "declare", summary: "Declare an exchange, a queue or a binding", run: runDeclare},
+		{name: "purge", summary: "Purge the messages of a queue", run: runPurge},
+		{name: "ping", summary: "Check that a broker accepts connections and channels", run: runPing},
+		{name: "topology", summary: "Apply a topology of exchanges, queues and bindings", run: runTopology},
+		{name: "bench", summary: "Benchmark pool capacities and selection strategies", run: runBench},
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], streams{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr})
+	stop()
+	os.Exit(code)
+}
+
+// run executes the subcommand named by the first argument, and returns the exit code of the tool.
+func run(ctx context.Context, args []string, streams streams) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(streams.stderr)
+		return 2
+	}
+
+	for _, command := range commands() {
+		if command.name != args[0] {
+			continue
+		}
+
+		err := command.run(ctx, args[1:], streams)
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		if err != nil {
+			printf(streams.stderr, "amqpx %s: %s\n", command.name, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	printf(streams.stderr, "amqpx: unknown command %q\n\n", args[0])
+	usage(streams.stderr)
+
+	return 2
+}
+
+// usage prints the commands of the tool.
+func usage(writer io.Writer) {
+	printf(writer, "Usage: amqpx <command> [flags]\n\nCommands:\n")
+	for _, command := range commands() {
+		printf(writer, "  %-10s %s\n", command.name, command.summary)
+	}
+	printf(writer, "\nRun \"amqpx <command> -h\" for the flags of a command.\n")
+}
+
+// printf writes a formatted message on given writer. Errors on the standard streams are ignored.
+func printf(writer io.Writer, format string, args ...interface{}) {
+	_, thr := fmt.Fprintf(writer, format, args...)
+	_ = thr
+}
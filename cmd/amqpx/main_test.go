@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func NewServer(t *testing.T) (*amqpxtest.Server, *amqpxtest.Broker) {
+	t.Helper()
+
+	broker := amqpxtest.NewBroker()
+	server, err := amqpxtest.NewServer(broker)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, server.Kill())
+	})
+
+	return server, broker
+}
+
+func Run(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(context.Background(), args, streams{
+		stdin:  strings.NewReader(stdin),
+		stdout: stdout,
+		stderr: stderr,
+	})
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Usage(t *testing.T) {
+	is := require.New(t)
+
+	code, _, stderr := Run(t, "")
+	is.Equal(2, code)
+	is.Contains(stderr, "Usage: amqpx <command> [flags]")
+
+	code, _, stderr = Run(t, "", "unknown")
+	is.Equal(2, code)
+	is.Contains(stderr, `unknown command "unknown"`)
+
+	code, _, stderr = Run(t, "", "publish", "-h")
+	is.Equal(2, code)
+	is.Contains(stderr, "-exchange")
+	is.Contains(stderr, "-tls.ca")
+}
+
+func TestRun_PublishConsume(t *testing.T) {
+	is := require.New(t)
+
+	server, broker := NewServer(t)
+	uri := server.URI()
+
+	code, stdout, stderr := Run(t, "", "declare", "queue", "-uri", uri, "-name", "events")
+	is.Equal(0, code, stderr)
+	is.Equal("queue events declared (0 message(s), 0 consumer(s))\n", stdout)
+
+	code, stdout, stderr = Run(t, "first\nsecond\n\xff\n", "publish", "-uri", uri, "-key", "events", "-lines",
+		"-content-type", "text/plain", "-header", "tenant=ulule", "-persistent")
+	is.Equal(0, code, stderr)
+	is.Equal("published 3 message(s)\n", stdout)
+	is.Len(broker.Messages("events"), 3)
+
+	code, stdout, stderr = Run(t, "", "consume", "-uri", uri, "-queue", "events", "-count", "3")
+	is.Equal(0, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	is.Len(lines, 3)
+
+	records := make([]record, len(lines))
+	for i, line := range lines {
+		is.NoError(json.Unmarshal([]byte(line), &records[i]))
+	}
+	is.Equal("first", records[0].Body)
+	is.Equal("events", records[0].RoutingKey)
+	is.Equal("text/plain", records[0].ContentType)
+	is.Equal("ulule", records[0].Headers["tenant"])
+	is.NotNil(records[0].Timestamp)
+	is.Equal("second", records[1].Body)
+	is.Equal("/w==", records[2].Body)
+	is.Equal("base64", records[2].Encoding)
+	is.Empty(broker.Messages("events"))
+}
+
+func TestRun_PublishFile(t *testing.T) {
+	is := require.New(t)
+
+	server, broker := NewServer(t)
+	uri := server.URI()
+
+	file := filepath.Join(t.TempDir(), "message.json")
+	is.NoError(os.WriteFile(file, []byte("{\n  \"id\": 42\n}\n"), 0o600))
+
+	code, _, stderr := Run(t, "", "declare", "queue", "-uri", uri, "-name", "events")
+	is.Equal(0, code, stderr)
+
+	code, stdout, stderr := Run(t, "", "publish", "-uri", uri, "-key", "events", "-file", file)
+	is.Equal(0, code, stderr)
+	is.Equal("published 1 message(s)\n", stdout)
+
+	messages := broker.Messages("events")
+	is.Len(messages, 1)
+	is.Equal("{\n  \"id\": 42\n}\n", string(messages[0].Body))
+
+	code, stdout, stderr = Run(t, "", "purge", "-uri", uri, "-queue", "events")
+	is.Equal(0, code, stderr)
+	is.Equal("purged 1 message(s) from queue events\n", stdout)
+	is.Empty(broker.Messages("events"))
+
+	code, _, stderr = Run(t, "", "purge", "-uri", uri)
+	is.Equal(1, code)
+	is.Contains(stderr, ErrQueueRequired.Error())
+}
+
+func TestRun_Declare(t *testing.T) {
+	is := require.New(t)
+
+	server, broker := NewServer(t)
+	uri := server.URI()
+
+	code, stdout, stderr := Run(t, "", "declare", "exchange", "-uri", uri, "-name", "events", "-type", "topic")
+	is.Equal(0, code, stderr)
+	is.Equal("exchange events declared\n", stdout)
+
+	kind, ok := broker.Exchange("events")
+	is.True(ok)
+	is.Equal("topic", kind)
+
+	code, _, stderr = Run(t, "", "declare", "queue", "-uri", uri, "-name", "orders")
+	is.Equal(0, code, stderr)
+
+	code, stdout, stderr = Run(t, "", "declare", "binding", "-uri", uri,
+		"-source", "events", "-destination", "orders", "-key", "orders.#")
+	is.Equal(0, code, stderr)
+	is.Equal("queue orders bound to events\n", stdout)
+
+	code, _, stderr = Run(t, "order", "publish", "-uri", uri, "-exchange", "events", "-key", "orders.created")
+	is.Equal(0, code, stderr)
+	is.Len(broker.Messages("orders"), 1)
+
+	code, _, stderr = Run(t, "", "declare", "user")
+	is.Equal(1, code)
+	is.Contains(stderr, ErrInvalidResource.Error())
+
+	code, _, stderr = Run(t, "", "declare", "exchange", "-uri", uri, "-name", "events", "-type", "fanout")
+	is.Equal(1, code)
+	is.Contains(stderr, "PRECONDITION_FAILED")
+}
+
+func TestRun_Topology(t *testing.T) {
+	is := require.New(t)
+
+	server, broker := NewServer(t)
+
+	topology := `{
+		"exchanges": [
+			{"name": "events", "type": "topic", "durable": true},
+			{"name": "orders", "type": "fanout", "durable": true}
+		],
+		"queues": [
+			{"name": "orders.created", "durable": true, "arguments": {"x-message-ttl": 60000}}
+		],
+		"bindings": [
+			{"source": "events", "destination": "orders", "destination_type": "exchange", "routing_key": "orders.#"},
+			{"source": "orders", "destination": "orders.created"}
+		]
+	}`
+
+	code, stdout, stderr := Run(t, topology, "topology", "apply", "-uri", server.URI())
+	is.Equal(0, code, stderr)
+	is.Equal("applied 2 exchange(s), 1 queue(s) and 2 binding(s)\n", stdout)
+
+	queue, ok := broker.Queue("orders.created")
+	is.True(ok)
+	is.Equal("orders.created", queue.Name)
+
+	code, _, stderr = Run(t, "created", "publish", "-uri", server.URI(), "-exchange", "events", "-key", "orders.1")
+	is.Equal(0, code, stderr)
+	is.Len(broker.Messages("orders.created"), 1)
+
+	code, _, stderr = Run(t, topology, "topology", "-uri", server.URI())
+	is.Equal(1, code)
+	is.Contains(stderr, ErrInvalidTopologyCommand.Error())
+
+	value, err := readTopology(strings.NewReader(topology))
+	is.NoError(err)
+	is.Equal(int64(60000), value.Queues[0].Arguments["x-message-ttl"])
+}
+
+func TestRun_Ping(t *testing.T) {
+	is := require.New(t)
+
+	server, _ := NewServer(t)
+	other, _ := NewServer(t)
+
+	code, stdout, stderr := Run(t, "", "ping", "-uri", server.URI()+","+other.URI(), "-capacity", "2")
+	is.Equal(0, code, stderr)
+	is.True(strings.HasPrefix(stdout, "pong in "))
+
+	is.NoError(server.Kill())
+
+	code, _, stderr = Run(t, "", "ping", "-uri", server.URI())
+	is.Equal(1, code)
+	is.Contains(stderr, "connection refused")
+}
+
+func TestClientFlags_TLS(t *testing.T) {
+	is := require.New(t)
+
+	flags := &clientFlags{}
+	config, err := flags.tlsConfig()
+	is.NoError(err)
+	is.Nil(config)
+
+	flags.tlsInsecure = true
+	config, err = flags.tlsConfig()
+	is.NoError(err)
+	is.True(config.InsecureSkipVerify)
+
+	flags.tlsCert = "client.pem"
+	_, err = flags.tlsConfig()
+	is.Equal(amqpx.ErrInvalidTLSKeyPair, errors.Cause(err))
+
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	is.NoError(os.WriteFile(file, []byte("invalid"), 0o600))
+	flags = &clientFlags{tlsCA: file}
+	_, err = flags.tlsConfig()
+	is.Equal(amqpx.ErrInvalidTLSCA, errors.Cause(err))
+}
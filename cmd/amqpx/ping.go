@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runPing connects to the broker and opens a channel, then prints how long it took.
+func runPing(ctx context.Context, args []string, streams streams) error {
+	set, client := newFlagSet("ping", streams)
+
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	channel, release, err := client.channel(streams)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = channel.Close()
+	if err != nil {
+		return err
+	}
+
+	printf(streams.stdout, "pong in %s\n", time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// headersFlag is a repeated flag of message headers, given as key=value.
+type headersFlag amqpx.Table
+
+// String implements flag.Value interface.
+func (e headersFlag) String() string {
+	pairs := []string{}
+	for key, value := range e {
+		pairs = append(pairs, key+"="+value.(string))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value interface.
+func (e headersFlag) Set(value string) error {
+	key, header, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return ErrInvalidHeader
+	}
+	e[key] = header
+	return nil
+}
+
+// publishFlags are the flags of the publish command.
+type publishFlags struct {
+	exchange    string
+	key         string
+	file        string
+	contentType string
+	persistent  bool
+	lines       bool
+	confirm     bool
+	headers     headersFlag
+}
+
+// runPublish publishes the content of stdin or a file: as a single message, or one message per line.
+func runPublish(ctx context.Context, args []string, streams streams) error {
+	set, client := newFlagSet("publish", streams)
+	flags := publishFlags{headers: headersFlag{}}
+	set.StringVar(&flags.exchange, "exchange", "", "Exchange to publish to, the default exchange if empty")
+	set.StringVar(&flags.key, "key", "", "Routing key, the queue name with the default exchange")
+	set.StringVar(&flags.file, "file", "", "File to publish, stdin if empty")
+	set.StringVar(&flags.contentType, "content-type", "", "Content type of messages")
+	set.BoolVar(&flags.persistent, "persistent", false, "Publish persistent messages")
+	set.BoolVar(&flags.lines, "lines", false, "Publish one message per line")
+	set.BoolVar(&flags.confirm, "confirm", true, "Wait for the broker to confirm messages")
+	set.Var(flags.headers, "header", "Message header as key=value, repeated for several headers")
+
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	bodies, err := readBodies(streams.stdin, flags.file, flags.lines)
+	if err != nil {
+		return err
+	}
+
+	channel, release, err := client.channel(streams)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = publish(ctx, channel, flags, bodies)
+	if err != nil {
+		return err
+	}
+
+	printf(streams.stdout, "published %d message(s)\n", len(bodies))
+
+	return nil
+}
+
+// readBodies reads the bodies of messages from given file, or from given reader if it's empty.
+func readBodies(stdin io.Reader, file string, lines bool) ([][]byte, error) {
+	reader := stdin
+	if file != "" {
+		handle, err := os.Open(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot open file")
+		}
+		defer func() {
+			thr := handle.Close()
+			_ = thr
+		}()
+		reader = handle
+	}
+
+	if !lines {
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read messages")
+		}
+		return [][]byte{body}, nil
+	}
+
+	bodies := [][]byte{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		if len(line) > 0 {
+			bodies = append(bodies, append([]byte{}, line...))
+		}
+	}
+
+	return bodies, errors.Wrap(scanner.Err(), "cannot read messages")
+}
+
+// publish publishes given bodies on given channel, and waits for their confirmation if required.
+func publish(ctx context.Context, channel amqpx.Channel, flags publishFlags, bodies [][]byte) error {
+	var confirms chan amqpx.Confirmation
+	if flags.confirm {
+		err := channel.Confirm(false)
+		if err != nil {
+			return err
+		}
+		confirms = channel.NotifyPublish(make(chan amqpx.Confirmation, len(bodies)))
+	}
+
+	mode := amqpx.Transient
+	if flags.persistent {
+		mode = amqpx.Persistent
+	}
+
+	for _, body := range bodies {
+		err := channel.PublishWithContext(ctx, flags.exchange, flags.key, false, false, amqpx.Publishing{
+			Headers:      amqpx.Table(flags.headers),
+			ContentType:  flags.contentType,
+			DeliveryMode: mode,
+			Timestamp:    time.Now(),
+			Body:         body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if confirms == nil {
+		return nil
+	}
+
+	return waitConfirms(ctx, confirms, len(bodies))
+}
+
+// waitConfirms waits for given number of confirmations.
+func waitConfirms(ctx context.Context, confirms chan amqpx.Confirmation, count int) error {
+	for i := 0; i < count; i++ {
+		select {
+		case confirm, ok := <-confirms:
+			if !ok {
+				return ErrConfirmationsClosed
+			}
+			if !confirm.Ack {
+				return errors.Wrapf(ErrMessageNotConfirmed, "delivery tag %d", confirm.DeliveryTag)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+)
+
+// runPurge removes the messages of a queue.
+func runPurge(ctx context.Context, args []string, streams streams) error {
+	set, client := newFlagSet("purge", streams)
+	queue := ""
+	set.StringVar(&queue, "queue", "", "Queue to purge")
+
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+	if queue == "" {
+		return ErrQueueRequired
+	}
+
+	channel, release, err := client.channel(streams)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	count, err := channel.QueuePurge(queue, false)
+	if err != nil {
+		return err
+	}
+
+	printf(streams.stdout, "purged %d message(s) from queue %s\n", count, queue)
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+// Binding destination types.
+const (
+	destinationQueue    = "queue"
+	destinationExchange = "exchange"
+)
+
+// exchangeSpec describes an exchange.
+type exchangeSpec struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Durable    bool        `json:"durable"`
+	AutoDelete bool        `json:"auto_delete"`
+	Internal   bool        `json:"internal"`
+	Arguments  amqpx.Table `json:"arguments"`
+}
+
+// declare declares the exchange on given channel. Its type defaults to direct.
+func (e exchangeSpec) declare(channel amqpx.Channel) error {
+	if e.Name == "" {
+		return ErrExchangeRequired
+	}
+
+	kind := e.Type
+	if kind == "" {
+		kind = amqpx.ExchangeDirect
+	}
+
+	err := channel.ExchangeDeclare(e.Name, kind, e.Durable, e.AutoDelete, e.Internal, false, e.Arguments)
+	return errors.Wrapf(err, "cannot declare exchange %s", e.Name)
+}
+
+// queueSpec describes a queue.
+type queueSpec struct {
+	Name       string      `json:"name"`
+	Durable    bool        `json:"durable"`
+	AutoDelete bool        `json:"auto_delete"`
+	Arguments  amqpx.Table `json:"arguments"`
+}
+
+// declare declares the queue on given channel.
+func (e queueSpec) declare(channel amqpx.Channel) (amqpx.Queue, error) {
+	if e.Name == "" {
+		return amqpx.Queue{}, ErrQueueRequired
+	}
+
+	queue, err := channel.QueueDeclare(e.Name, e.Durable, e.AutoDelete, false, false, e.Arguments)
+	return queue, errors.Wrapf(err, "cannot declare queue %s", e.Name)
+}
+
+// bindingSpec describes a binding from an exchange to a queue or another exchange.
+type bindingSpec struct {
+	Source          string      `json:"source"`
+	Destination     string      `json:"destination"`
+	DestinationType string      `json:"destination_type"`
+	RoutingKey      string      `json:"routing_key"`
+	Arguments       amqpx.Table `json:"arguments"`
+}
+
+// declare declares the binding on given channel. Its destination type defaults to queue.
+func (e bindingSpec) declare(channel amqpx.Channel) error {
+	if e.Source == "" || e.Destination == "" {
+		return ErrBindingRequired
+	}
+
+	var err error
+	switch e.DestinationType {
+	case "", destinationQueue:
+		err = channel.QueueBind(e.Destination, e.RoutingKey, e.Source, false, e.Arguments)
+	case destinationExchange:
+		err = channel.ExchangeBind(e.Destination, e.RoutingKey, e.Source, false, e.Arguments)
+	default:
+		return errors.Wrapf(ErrInvalidDestinationType, "%q", e.DestinationType)
+	}
+
+	return errors.Wrapf(err, "cannot bind %s to %s", e.Destination, e.Source)
+}
+
+// topology is a set of exchanges, queues and bindings.
+type topology struct {
+	Exchanges []exchangeSpec `json:"exchanges"`
+	Queues    []queueSpec    `json:"queues"`
+	Bindings  []bindingSpec  `json:"bindings"`
+}
+
+// readTopology reads a JSON topology. Integer arguments are kept as integers, as expected by RabbitMQ.
+func readTopology(reader io.Reader) (topology, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	value := topology{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return topology{}, errors.Wrap(err, "cannot read topology")
+	}
+
+	for i := range value.Exchanges {
+		value.Exchanges[i].Arguments = normalizeTable(value.Exchanges[i].Arguments)
+	}
+	for i := range value.Queues {
+		value.Queues[i].Arguments = normalizeTable(value.Queues[i].Arguments)
+	}
+	for i := range value.Bindings {
+		value.Bindings[i].Arguments = normalizeTable(value.Bindings[i].Arguments)
+	}
+
+	return value, nil
+}
+
+// normalizeTable converts the JSON numbers of given table to integers or floats.
+func normalizeTable(table amqpx.Table) amqpx.Table {
+	for key, value := range table {
+		table[key] = normalizeValue(value)
+	}
+	return table
+}
+
+// normalizeValue converts the JSON numbers of given value to integers or floats.
+func normalizeValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		integer, err := value.Int64()
+		if err == nil {
+			return integer
+		}
+		float, _ := value.Float64()
+		return float
+	case map[string]interface{}:
+		return normalizeTable(value)
+	case []interface{}:
+		for i := range value {
+			value[i] = normalizeValue(value[i])
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// apply declares the exchanges, then the queues, then the bindings of the topology.
+func (e topology) apply(channel amqpx.Channel) error {
+	for _, exchange := range e.Exchanges {
+		err := exchange.declare(channel)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, queue := range e.Queues {
+		_, err := queue.declare(channel)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, binding := range e.Bindings {
+		err := binding.declare(channel)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runTopology applies a topology read from stdin or a file.
+func runTopology(ctx context.Context, args []string, streams streams) error {
+	if len(args) == 0 || args[0] != "apply" {
+		return ErrInvalidTopologyCommand
+	}
+
+	set, client := newFlagSet("topology apply", streams)
+	file := ""
+	set.StringVar(&file, "file", "", "JSON topology file, stdin if empty")
+
+	err := set.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	reader := streams.stdin
+	if file != "" {
+		handle, err := os.Open(file)
+		if err != nil {
+			return errors.Wrap(err, "cannot open file")
+		}
+		defer func() {
+			thr := handle.Close()
+			_ = thr
+		}()
+		reader = handle
+	}
+
+	value, err := readTopology(reader)
+	if err != nil {
+		return err
+	}
+
+	channel, release, err := client.channel(streams)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = value.apply(channel)
+	if err != nil {
+		return err
+	}
+
+	printf(streams.stdout, "applied %d exchange(s), %d queue(s) and %d binding(s)\n",
+		len(value.Exchanges), len(value.Queues), len(value.Bindings))
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package amqpx
+
+import (
+	"encoding/json"
+)
+
+// Codec describes how a message is encoded to and decoded from an amqp payload.
+type Codec interface {
+	// ContentType returns the MIME type of encoded payloads.
+	ContentType() string
+
+	// Encode returns the payload of given value.
+	Encode(value interface{}) ([]byte, error)
+
+	// Decode parses given payload and stores the result in the value pointed to by value.
+	Decode(data []byte, value interface{}) error
+}
+
+// JSONCodec is a Codec using encoding/json.
+type JSONCodec struct{}
+
+// ContentType implements Codec interface.
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// Encode implements Codec interface.
+func (JSONCodec) Encode(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Decode implements Codec interface.
+func (JSONCodec) Decode(data []byte, value interface{}) error {
+	return json.Unmarshal(data, value)
+}
+
+var _ Codec = (*JSONCodec)(nil)
@@ -0,0 +1,24 @@
+package amqpx_test
+
+import (
+	"testing"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestJSONCodec(t *testing.T) {
+	is := NewRunner(t)
+
+	codec := amqpx.JSONCodec{}
+	is.Equal("application/json", codec.ContentType())
+
+	body, err := codec.Encode(&Event{Message: "hello"})
+	is.NoError(err)
+	is.Equal(`{"Message":"hello"}`, string(body))
+
+	event := &Event{}
+	is.NoError(codec.Decode(body, event))
+	is.Equal("hello", event.Message)
+
+	is.Error(codec.Decode([]byte("{"), event))
+}
@@ -0,0 +1,461 @@
+package amqpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Config describes a Client and its Dialer, so it can be loaded from environment variables or a JSON document.
+// Zero values use the defaults of the underlying options.
+//
+// In JSON, durations are strings such as "10s", like time.ParseDuration expects.
+// In environment variables, URIs are comma-separated.
+type Config struct {
+	// URIs are the broker URIs. Several URIs require the cluster mode.
+	URIs []string `json:"uris,omitempty" env:"URIS"`
+	// Cluster uses a ClusterDialer instead of a SimpleDialer.
+	Cluster bool `json:"cluster,omitempty" env:"CLUSTER"`
+	// Timeout is the dialer timeout.
+	Timeout time.Duration `json:"timeout" env:"TIMEOUT"`
+	// Heartbeat is the dialer heartbeat interval.
+	Heartbeat time.Duration `json:"heartbeat" env:"HEARTBEAT"`
+	// Capacity is the number of connections of the pool.
+	Capacity int `json:"capacity,omitempty" env:"CAPACITY"`
+	// MinConnections is the number of connections of the pool required to start. The others are opened in the
+	// background.
+	MinConnections int `json:"min_connections,omitempty" env:"MIN_CONNECTIONS"`
+	// LazyConnections starts without waiting for any connection of the pool.
+	LazyConnections bool `json:"lazy_connections,omitempty" env:"LAZY_CONNECTIONS"`
+	// StartupParallelism is the number of connections of the pool dialed concurrently at startup.
+	StartupParallelism int `json:"startup_parallelism,omitempty" env:"STARTUP_PARALLELISM"`
+	// StartupTimeout is the overall time to wait for the connections of the pool at startup.
+	StartupTimeout time.Duration `json:"startup_timeout" env:"STARTUP_TIMEOUT"`
+	// ConnectionMaxLifetime is the time after which a connection of the pool is replaced. It's disabled if empty.
+	ConnectionMaxLifetime time.Duration `json:"connection_max_lifetime" env:"CONNECTION_MAX_LIFETIME"`
+	// ConnectionMaxLifetimeJitter is the maximum random time removed from the lifetime of each connection.
+	ConnectionMaxLifetimeJitter time.Duration `json:"connection_max_lifetime_jitter" env:"CONNECTION_MAX_LIFETIME_JITTER"`
+	// WithoutPool uses a single connection instead of a connections pool.
+	WithoutPool bool `json:"without_pool,omitempty" env:"WITHOUT_POOL"`
+	// LoggerLevel is the level of the default logger: debug, info, warn or error. It's disabled if empty.
+	LoggerLevel string `json:"logger_level,omitempty" env:"LOGGER_LEVEL"`
+	// TLSCAFile is the path of the certificate authorities used to verify the brokers, in PEM format.
+	TLSCAFile string `json:"tls_ca_file,omitempty" env:"TLS_CA_FILE"`
+	// TLSCertFile is the path of the client certificate, in PEM format.
+	TLSCertFile string `json:"tls_cert_file,omitempty" env:"TLS_CERT_FILE"`
+	// TLSKeyFile is the path of the client key, in PEM format.
+	TLSKeyFile string `json:"tls_key_file,omitempty" env:"TLS_KEY_FILE"`
+	// ReconnectBackoffMin is the minimum delay between two attempts to replace a lost connection.
+	ReconnectBackoffMin time.Duration `json:"reconnect_backoff_min" env:"RECONNECT_BACKOFF_MIN"`
+	// ReconnectBackoffMax is the maximum delay between two attempts to replace a lost connection.
+	ReconnectBackoffMax time.Duration `json:"reconnect_backoff_max" env:"RECONNECT_BACKOFF_MAX"`
+}
+
+// ConfigFromEnv returns a Config read from the environment variables with the given prefix,
+// such as AMQPX_URIS or AMQPX_CAPACITY for the "AMQPX" prefix. Unset variables keep their zero value.
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	config := Config{}
+	value := reflect.ValueOf(&config).Elem()
+	kind := value.Type()
+
+	for i := 0; i < kind.NumField(); i++ {
+		name := prefix + kind.Field(i).Tag.Get("env")
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		err := setField(value.Field(i), strings.TrimSpace(raw))
+		if err != nil {
+			return Config{}, errors.Wrapf(err, "invalid %s", name)
+		}
+	}
+
+	return config, nil
+}
+
+// setField parses given raw value into given field.
+func setField(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case []string:
+		uris := []string{}
+		for _, uri := range strings.Split(raw, ",") {
+			if strings.TrimSpace(uri) != "" {
+				uris = append(uris, strings.TrimSpace(uri))
+			}
+		}
+		field.Set(reflect.ValueOf(uris))
+	case bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case time.Duration:
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	case int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	default:
+		field.SetString(raw)
+	}
+
+	return nil
+}
+
+// configAlias is a Config without its JSON methods.
+type configAlias Config
+
+// configJSON is the JSON representation of a Config: its durations shadow the ones of the embedded Config,
+// so they are encoded as strings.
+type configJSON struct {
+	*configAlias
+	Timeout                     *duration `json:"timeout,omitempty"`
+	Heartbeat                   *duration `json:"heartbeat,omitempty"`
+	StartupTimeout              *duration `json:"startup_timeout,omitempty"`
+	ConnectionMaxLifetime       *duration `json:"connection_max_lifetime,omitempty"`
+	ConnectionMaxLifetimeJitter *duration `json:"connection_max_lifetime_jitter,omitempty"`
+	ReconnectBackoffMin         *duration `json:"reconnect_backoff_min,omitempty"`
+	ReconnectBackoffMax         *duration `json:"reconnect_backoff_max,omitempty"`
+}
+
+// newConfigJSON returns the JSON representation of given Config, which reads and writes its fields.
+func newConfigJSON(config *Config) *configJSON {
+	return &configJSON{
+		configAlias:                 (*configAlias)(config),
+		Timeout:                     (*duration)(&config.Timeout),
+		Heartbeat:                   (*duration)(&config.Heartbeat),
+		StartupTimeout:              (*duration)(&config.StartupTimeout),
+		ConnectionMaxLifetime:       (*duration)(&config.ConnectionMaxLifetime),
+		ConnectionMaxLifetimeJitter: (*duration)(&config.ConnectionMaxLifetimeJitter),
+		ReconnectBackoffMin:         (*duration)(&config.ReconnectBackoffMin),
+		ReconnectBackoffMax:         (*duration)(&config.ReconnectBackoffMax),
+	}
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (e Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newConfigJSON(&e))
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (e *Config) UnmarshalJSON(data []byte) error {
+	config := Config{}
+	err := json.Unmarshal(data, newConfigJSON(&config))
+	if err != nil {
+		return err
+	}
+
+	*e = config
+	return nil
+}
+
+// duration is a time.Duration encoded in JSON as a string, such as "10s".
+type duration time.Duration
+
+// MarshalJSON implements json.Marshaler interface.
+func (e duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(e).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (e *duration) UnmarshalJSON(data []byte) error {
+	raw := ""
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return errors.Wrap(ErrInvalidConfigDuration, string(data))
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return errors.Wrap(ErrInvalidConfigDuration, raw)
+	}
+
+	*e = duration(value)
+	return nil
+}
+
+// Validate checks every value of the configuration, and returns all the problems found.
+func (e Config) Validate() error {
+	list := errorList{}
+
+	list.add(e.validateURIs())
+	list.add(e.validateDialer())
+	list.add(e.validatePool())
+	list.add(e.validateClient())
+	list.add(e.validateTLS())
+
+	return list.err()
+}
+
+// validateURIs checks the broker URIs and the cluster mode.
+func (e Config) validateURIs() error {
+	if len(e.URIs) == 0 {
+		return errors.Wrap(ErrBrokerURIRequired, "uris")
+	}
+
+	list := errorList{}
+	for _, uri := range e.URIs {
+		_, err := amqp.ParseURI(uri)
+		if err != nil {
+			list.add(errors.Wrapf(ErrInvalidBrokerURI, "uris: %s: %s", RedactURI(uri), err))
+		}
+	}
+
+	if len(e.URIs) > 1 && !e.Cluster {
+		list.add(errors.Wrap(ErrClusterModeRequired, "uris"))
+	}
+
+	return list.err()
+}
+
+// validateDialer checks the dialer timeout and heartbeat.
+func (e Config) validateDialer() error {
+	list := errorList{}
+
+	if e.Timeout < 0 {
+		list.add(errors.Wrapf(ErrInvalidDialerTimeout, "timeout: %s", e.Timeout))
+	}
+	if e.Heartbeat < 0 {
+		list.add(errors.Wrapf(ErrInvalidDialerHeartbeat, "heartbeat: %s", e.Heartbeat))
+	}
+
+	return list.err()
+}
+
+// validatePool checks the pool capacity and its startup.
+func (e Config) validatePool() error {
+	list := errorList{}
+
+	if e.Capacity < 0 || (e.Capacity > 0 && e.WithoutPool) {
+		list.add(errors.Wrapf(ErrInvalidConnectionsPoolCapacity, "capacity: %d", e.Capacity))
+	}
+
+	if e.MinConnections < 0 || e.MinConnections > e.capacity() || (e.MinConnections > 0 && e.LazyConnections) {
+		list.add(errors.Wrapf(ErrInvalidMinConnections, "min_connections: %d", e.MinConnections))
+	}
+
+	if e.StartupParallelism < 0 {
+		list.add(errors.Wrapf(ErrInvalidStartupParallelism, "startup_parallelism: %d", e.StartupParallelism))
+	}
+	if e.StartupTimeout < 0 {
+		list.add(errors.Wrapf(ErrInvalidStartupTimeout, "startup_timeout: %s", e.StartupTimeout))
+	}
+	if e.ConnectionMaxLifetime < 0 || e.ConnectionMaxLifetimeJitter < 0 ||
+		(e.ConnectionMaxLifetimeJitter > 0 && e.ConnectionMaxLifetimeJitter >= e.ConnectionMaxLifetime) {
+		list.add(errors.Wrapf(ErrInvalidConnectionMaxLifetime, "connection_max_lifetime: %s-%s",
+			e.ConnectionMaxLifetime, e.ConnectionMaxLifetimeJitter))
+	}
+
+	return list.err()
+}
+
+// validateClient checks the logger level and the reconnect backoff.
+func (e Config) validateClient() error {
+	list := errorList{}
+
+	if LoggerLevelFromString(e.LoggerLevel) == LoggerLevelDisabled && strings.TrimSpace(e.LoggerLevel) != "" {
+		list.add(errors.Wrapf(ErrInvalidLoggerLevel, "logger_level: %q", e.LoggerLevel))
+	}
+
+	if e.ReconnectBackoffMin < 0 || e.ReconnectBackoffMax < 0 ||
+		(e.ReconnectBackoffMax > 0 && e.reconnectBackoffMin() > e.ReconnectBackoffMax) {
+		list.add(errors.Wrapf(ErrInvalidReconnectBackoff, "reconnect_backoff: %s-%s",
+			e.ReconnectBackoffMin, e.ReconnectBackoffMax))
+	}
+
+	return list.err()
+}
+
+// validateTLS checks that the TLS files exist, and that the client certificate comes with its key.
+func (e Config) validateTLS() error {
+	list := errorList{}
+
+	if (e.TLSCertFile == "") != (e.TLSKeyFile == "") {
+		list.add(errors.Wrap(ErrInvalidTLSKeyPair, "tls_cert_file, tls_key_file"))
+	}
+
+	for _, file := range []string{e.TLSCAFile, e.TLSCertFile, e.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		_, err := os.Stat(file)
+		if err != nil {
+			list.add(errors.Wrap(err, "tls"))
+		}
+	}
+
+	return list.err()
+}
+
+// capacity returns the capacity of the connections pool, or its default value.
+func (e Config) capacity() int {
+	if e.Capacity > 0 {
+		return e.Capacity
+	}
+	return DefaultConnectionsCapacity
+}
+
+// reconnectBackoffMin returns the minimum reconnect backoff, or its default value.
+func (e Config) reconnectBackoffMin() time.Duration {
+	if e.ReconnectBackoffMin > 0 {
+		return e.ReconnectBackoffMin
+	}
+	return DefaultReconnectBackoffMin
+}
+
+// reconnectBackoffMax returns the maximum reconnect backoff, or its default value.
+// The default value is raised to the minimum if needed.
+func (e Config) reconnectBackoffMax() time.Duration {
+	if e.ReconnectBackoffMax > 0 {
+		return e.ReconnectBackoffMax
+	}
+	if e.reconnectBackoffMin() > DefaultReconnectBackoffMax {
+		return e.reconnectBackoffMin()
+	}
+	return DefaultReconnectBackoffMax
+}
+
+// Dialer returns the Dialer described by the configuration.
+// Given options are applied after the ones of the configuration, so they can override them.
+func (e Config) Dialer(options ...DialerOption) (Dialer, error) {
+	if len(e.URIs) == 0 {
+		return nil, errors.Wrap(ErrBrokerURIRequired, ErrMessageCannotCreateDialer)
+	}
+
+	opts := []DialerOption{}
+	if e.Timeout > 0 {
+		opts = append(opts, WithDialerTimeout(e.Timeout))
+	}
+	if e.Heartbeat > 0 {
+		opts = append(opts, WithDialerHeartbeat(e.Heartbeat))
+	}
+
+	config, err := e.TLSConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, ErrMessageCannotCreateDialer)
+	}
+	if config != nil {
+		opts = append(opts, WithDialerTLSConfig(config))
+	}
+
+	opts = append(opts, options...)
+	if e.Cluster {
+		return ClusterDialer(e.URIs, opts...)
+	}
+
+	return SimpleDialer(e.URIs[0], opts...)
+}
+
+// TLSConfig loads the TLS configuration described by the configuration, or returns nil if there is none.
+func (e Config) TLSConfig() (*tls.Config, error) {
+	if e.TLSCAFile == "" && e.TLSCertFile == "" && e.TLSKeyFile == "" {
+		return nil, nil
+	}
+	if (e.TLSCertFile == "") != (e.TLSKeyFile == "") {
+		return nil, ErrInvalidTLSKeyPair
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if e.TLSCAFile != "" {
+		pem, err := os.ReadFile(e.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Wrap(ErrInvalidTLSCA, e.TLSCAFile)
+		}
+	}
+
+	if e.TLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(e.TLSCertFile, e.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// Options returns the ClientOptions described by the configuration.
+func (e Config) Options() []ClientOption {
+	options := []ClientOption{
+		WithReconnectBackoff(e.reconnectBackoffMin(), e.reconnectBackoffMax()),
+	}
+
+	if e.WithoutPool {
+		options = append(options, WithoutConnectionsPool())
+	} else {
+		options = append(options, e.poolOptions()...)
+	}
+
+	if e.LoggerLevel != "" {
+		options = append(options, WithLoggerLevel(LoggerLevelFromString(e.LoggerLevel)))
+	}
+
+	return options
+}
+
+// poolOptions returns the ClientOptions of the connections pool.
+func (e Config) poolOptions() []ClientOption {
+	options := []ClientOption{}
+	if e.Capacity > 0 {
+		options = append(options, WithCapacity(e.Capacity))
+	}
+	if e.MinConnections > 0 {
+		options = append(options, WithMinConnections(e.MinConnections))
+	}
+	if e.LazyConnections {
+		options = append(options, WithLazyConnections())
+	}
+	if e.StartupParallelism > 0 {
+		options = append(options, WithStartupParallelism(e.StartupParallelism))
+	}
+	if e.StartupTimeout > 0 {
+		options = append(options, WithStartupTimeout(e.StartupTimeout))
+	}
+	if e.ConnectionMaxLifetime > 0 {
+		options = append(options, WithConnectionMaxLifetime(e.ConnectionMaxLifetime, e.ConnectionMaxLifetimeJitter))
+	}
+	return options
+}
+
+// NewFromConfig validates given configuration, then returns a new Client with its Dialer and options.
+// Given options are applied after the ones of the configuration, so they can override them.
+func NewFromConfig(config Config, options ...ClientOption) (Client, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, ErrMessageCannotCreateClient)
+	}
+
+	dialer, err := config.Dialer()
+	if err != nil {
+		return nil, errors.Wrap(err, ErrMessageCannotCreateClient)
+	}
+
+	return New(dialer, append(config.Options(), options...)...)
+}
@@ -0,0 +1,169 @@
+package amqpx_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func TestConfig_FromEnv(t *testing.T) {
+	is := NewRunner(t)
+
+	t.Setenv("AMQPX_URIS", "amqp://one:5672/, amqp://two:5672/")
+	t.Setenv("AMQPX_CLUSTER", "true")
+	t.Setenv("AMQPX_TIMEOUT", "3s")
+	t.Setenv("AMQPX_CAPACITY", "4")
+	t.Setenv("AMQPX_MIN_CONNECTIONS", "2")
+	t.Setenv("AMQPX_LOGGER_LEVEL", "warn")
+	t.Setenv("AMQPX_RECONNECT_BACKOFF_MAX", "2s")
+
+	config, err := amqpx.ConfigFromEnv("AMQPX")
+	is.NoError(err)
+	is.Equal([]string{"amqp://one:5672/", "amqp://two:5672/"}, config.URIs)
+	is.True(config.Cluster)
+	is.Equal(3*time.Second, config.Timeout)
+	is.Equal(time.Duration(0), config.Heartbeat)
+	is.Equal(4, config.Capacity)
+	is.Equal(2, config.MinConnections)
+	is.Equal("warn", config.LoggerLevel)
+	is.Equal(2*time.Second, config.ReconnectBackoffMax)
+	is.NoError(config.Validate())
+
+	t.Setenv("AMQPX_CAPACITY", "four")
+	_, err = amqpx.ConfigFromEnv("AMQPX_")
+	is.Error(err)
+	is.Contains(err.Error(), "invalid AMQPX_CAPACITY")
+}
+
+func TestConfig_JSON(t *testing.T) {
+	is := NewRunner(t)
+
+	config := amqpx.Config{}
+	err := json.Unmarshal([]byte(`{
+		"uris": ["amqp://localhost:5672/"],
+		"timeout": "5s",
+		"heartbeat": "1m",
+		"startup_timeout": "30s",
+		"without_pool": true,
+		"tls_ca_file": "/etc/amqpx/ca.pem"
+	}`), &config)
+	is.NoError(err)
+	is.Equal([]string{"amqp://localhost:5672/"}, config.URIs)
+	is.Equal(5*time.Second, config.Timeout)
+	is.Equal(time.Minute, config.Heartbeat)
+	is.Equal(30*time.Second, config.StartupTimeout)
+	is.True(config.WithoutPool)
+	is.Equal("/etc/amqpx/ca.pem", config.TLSCAFile)
+
+	data, err := json.Marshal(config)
+	is.NoError(err)
+	other := amqpx.Config{}
+	is.NoError(json.Unmarshal(data, &other))
+	is.Equal(config, other)
+
+	err = json.Unmarshal([]byte(`{"timeout": 5}`), &config)
+	is.True(errors.Is(err, amqpx.ErrInvalidConfigDuration))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	is := NewRunner(t)
+
+	err := amqpx.Config{}.Validate()
+	is.Equal(amqpx.ErrBrokerURIRequired, errors.Cause(err))
+
+	_, err = amqpx.Config{}.Dialer()
+	is.Equal(amqpx.ErrBrokerURIRequired, errors.Cause(err))
+
+	err = amqpx.Config{
+		URIs:                        []string{"amqp://localhost:5672/", "http://localhost/"},
+		Timeout:                     -time.Second,
+		Capacity:                    -1,
+		MinConnections:              20,
+		StartupTimeout:              -time.Second,
+		ConnectionMaxLifetimeJitter: time.Minute,
+		LoggerLevel:                 "verbose",
+		TLSCertFile:                 "cert.pem",
+		ReconnectBackoffMin:         2 * time.Second,
+		ReconnectBackoffMax:         time.Second,
+	}.Validate()
+	is.Error(err)
+
+	expected := []error{
+		amqpx.ErrInvalidBrokerURI,
+		amqpx.ErrClusterModeRequired,
+		amqpx.ErrInvalidDialerTimeout,
+		amqpx.ErrInvalidConnectionsPoolCapacity,
+		amqpx.ErrInvalidMinConnections,
+		amqpx.ErrInvalidStartupTimeout,
+		amqpx.ErrInvalidConnectionMaxLifetime,
+		amqpx.ErrInvalidLoggerLevel,
+		amqpx.ErrInvalidReconnectBackoff,
+		amqpx.ErrInvalidTLSKeyPair,
+	}
+	for _, target := range expected {
+		is.True(errors.Is(err, target), target.Error())
+	}
+	is.False(errors.Is(err, amqpx.ErrInvalidDialerHeartbeat))
+}
+
+func TestConfig_NewFromConfig(t *testing.T) {
+	is := NewRunner(t)
+
+	broker := amqpxtest.NewBroker()
+	server := NewServer(t, broker)
+	other := NewServer(t, broker)
+
+	client, err := amqpx.NewFromConfig(amqpx.Config{
+		URIs:      []string{server.URI(), other.URI()},
+		Cluster:   true,
+		Timeout:   time.Second,
+		Heartbeat: time.Second,
+		Capacity:  4,
+	})
+	is.NoError(err)
+	is.Equal(2, server.Connections())
+	is.Equal(2, other.Connections())
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+	is.NoError(client.Close())
+
+	client, err = amqpx.NewFromConfig(amqpx.Config{URIs: []string{server.URI()}, WithoutPool: true},
+		amqpx.WithLoggerLevel(amqpx.LoggerLevelError))
+	is.NoError(err)
+	is.Equal(1, server.Connections())
+	is.NoError(client.Close())
+
+	client, err = amqpx.NewFromConfig(amqpx.Config{URIs: []string{server.URI(), other.URI()}})
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrClusterModeRequired))
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	is.NoError(os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+	client, err = amqpx.NewFromConfig(amqpx.Config{URIs: []string{server.URI()}, TLSCAFile: path})
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrInvalidTLSCA))
+}
+
+func TestConfig_TLSConfig(t *testing.T) {
+	is := NewRunner(t)
+
+	config, err := amqpx.Config{}.TLSConfig()
+	is.NoError(err)
+	is.Nil(config)
+
+	_, err = amqpx.Config{TLSKeyFile: "key.pem"}.TLSConfig()
+	is.Equal(amqpx.ErrInvalidTLSKeyPair, errors.Cause(err))
+
+	_, err = amqpx.Config{URIs: []string{"amqps://127.0.0.1/"}, TLSCertFile: "cert.pem"}.Dialer()
+	is.Equal(amqpx.ErrInvalidTLSKeyPair, errors.Cause(err))
+}
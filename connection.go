@@ -0,0 +1,50 @@
+package amqpx
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// driverConnection describes an amqp connection, so clients don't depend on a driver's concrete types.
+type driverConnection interface {
+	Channel() (Channel, error)
+	NotifyClose(receiver chan *AMQPError) chan *AMQPError
+	LocalAddr() net.Addr
+	IsClosed() bool
+	Close() error
+}
+
+// amqpConnection is a driverConnection using https://github.com/rabbitmq/amqp091-go.
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+// Channel implements driverConnection interface.
+func (e amqpConnection) Channel() (Channel, error) {
+	channel, err := e.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// closeOpenConnection closes given connection unless it's already closed, such as when it was dropped by the broker:
+// such connection has not failed to close, so no error is returned.
+// An AMQP error returned by Close is the reason of a shutdown which happened while it was waiting for the broker,
+// so the connection was closed by the other side in the meantime. Only failures to request the close are returned.
+func closeOpenConnection(connection driverConnection) error {
+	if connection.IsClosed() {
+		return nil
+	}
+
+	err := connection.Close()
+	var reply *amqp.Error
+	if errors.As(err, &reply) {
+		return nil
+	}
+	return err
+}
+
+var _ driverConnection = (*amqpConnection)(nil)
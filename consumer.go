@@ -0,0 +1,145 @@
+package amqpx
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ConsumeFunc handles a decoded message of type T.
+// If it returns nil, the delivery is acknowledged, otherwise it's given to the consumer's ErrorHandler,
+// unless the delivery was already acknowledged or rejected.
+type ConsumeFunc[T any] func(ctx context.Context, message T, delivery amqp.Delivery) error
+
+// Consumer consumes messages of type T from a queue using a Client.
+// Messages are decoded with the Consumer's codec, which is JSON by default.
+type Consumer[T any] struct {
+	consumerOptions
+	client  Client
+	queue   string
+	handler ConsumeFunc[T]
+	next    Handler
+}
+
+// NewConsumer returns a new Consumer for given queue.
+func NewConsumer[T any](client Client, queue string, handler ConsumeFunc[T],
+	options ...ConsumerOption) (*Consumer[T], error) {
+
+	if client == nil {
+		return nil, errors.Wrap(ErrClientRequired, ErrMessageCannotCreateConsumer)
+	}
+	if handler == nil {
+		return nil, errors.Wrap(ErrHandlerRequired, ErrMessageCannotCreateConsumer)
+	}
+
+	opts := newConsumerOptions()
+	for _, option := range options {
+		err := option.apply(&opts)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrMessageCannotCreateConsumer)
+		}
+	}
+
+	consumer := &Consumer[T]{
+		consumerOptions: opts,
+		client:          client,
+		queue:           queue,
+		handler:         handler,
+	}
+	middlewares := append([]Middleware{TraceMiddleware(opts.tracer)}, opts.middlewares...)
+	consumer.next = Chain(consumer.decode, middlewares...)
+
+	return consumer, nil
+}
+
+// Queue returns the queue used by consumer.
+func (e *Consumer[T]) Queue() string {
+	return e.queue
+}
+
+// Consume acquires a new channel from client and handles deliveries from consumer's queue.
+// It blocks until given context is done, or until the client starts to shut down, in which case it returns nil
+// once the current delivery is handled. Otherwise, it blocks until the channel is closed: just call Consume again
+// to recycle it.
+func (e *Consumer[T]) Consume(ctx context.Context) error {
+	channel, err := e.channel()
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotConsumeQueue)
+	}
+	defer func() {
+		thr := channel.Close()
+		_ = thr
+	}()
+
+	if e.prefetch > 0 {
+		err = channel.Qos(e.prefetch, 0, false)
+		if err != nil {
+			return errors.Wrap(err, ErrMessageCannotConsumeQueue)
+		}
+	}
+
+	deliveries, err := channel.Consume(e.queue, e.tag, false, false, false, false, nil)
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotConsumeQueue)
+	}
+
+	var stopping <-chan struct{}
+	if notifier, ok := e.client.(ShutdownNotifier); ok {
+		stopping = notifier.ShuttingDown()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-stopping:
+			return nil
+
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return errors.Wrap(ErrDeliveriesClosed, ErrMessageCannotConsumeQueue)
+			}
+			e.handle(ctx, delivery)
+		}
+	}
+}
+
+// channel acquires a new channel from client, using its dedicated connections for consumers if any.
+func (e *Consumer[T]) channel() (Channel, error) {
+	if client, ok := e.client.(ConsumeChanneler); ok {
+		return client.ConsumeChannel()
+	}
+	return e.client.Channel()
+}
+
+// handle forwards given delivery to consumer's middlewares and handler.
+// If the delivery was not already acknowledged or rejected, it's acknowledged on success
+// or given to consumer's error handler on failure.
+func (e *Consumer[T]) handle(ctx context.Context, delivery amqp.Delivery) {
+	delivery.Acknowledger = &onceAcknowledger{acknowledger: delivery.Acknowledger}
+
+	err := e.next(ctx, delivery)
+	if err != nil {
+		e.errorHandler(delivery, err)
+		return
+	}
+
+	err = delivery.Ack(false)
+	if err != nil {
+		e.errorHandler(delivery, errors.Wrap(err, ErrMessageCannotAckMessage))
+	}
+}
+
+// decode decodes given delivery and forwards it to consumer's handler.
+func (e *Consumer[T]) decode(ctx context.Context, delivery amqp.Delivery) error {
+	var message T
+
+	err := e.codec.Decode(delivery.Body, &message)
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotDecodeMessage)
+	}
+
+	return e.handler(ctx, message, delivery)
+}
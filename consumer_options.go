@@ -0,0 +1,109 @@
+package amqpx
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrorHandler is called when a delivery cannot be decoded or handled.
+// It's responsible for acknowledging, rejecting or requeuing the delivery.
+type ErrorHandler func(delivery amqp.Delivery, err error)
+
+// defaultErrorHandler rejects the delivery without requeuing it.
+func defaultErrorHandler(delivery amqp.Delivery, err error) {
+	thr := delivery.Nack(false, false)
+	_ = thr
+}
+
+// ConsumerOption is used to define Consumer options.
+type ConsumerOption interface {
+	apply(*consumerOptions) error
+}
+
+type consumerOption func(*consumerOptions) error
+
+func (o consumerOption) apply(instance *consumerOptions) error {
+	return o(instance)
+}
+
+type consumerOptions struct {
+	codec        Codec
+	tracer       Tracer
+	errorHandler ErrorHandler
+	middlewares  []Middleware
+	tag          string
+	prefetch     int
+}
+
+func newConsumerOptions() consumerOptions {
+	return consumerOptions{
+		codec:        JSONCodec{},
+		tracer:       noopTracer{},
+		errorHandler: defaultErrorHandler,
+	}
+}
+
+// WithConsumerCodec will configure a Consumer with the given codec.
+func WithConsumerCodec(codec Codec) ConsumerOption {
+	return consumerOption(func(options *consumerOptions) error {
+		if codec == nil {
+			return ErrCodecRequired
+		}
+		options.codec = codec
+		return nil
+	})
+}
+
+// WithConsumerErrorHandler will configure a Consumer with the given error handler.
+func WithConsumerErrorHandler(handler ErrorHandler) ConsumerOption {
+	return consumerOption(func(options *consumerOptions) error {
+		if handler == nil {
+			return ErrErrorHandlerRequired
+		}
+		options.errorHandler = handler
+		return nil
+	})
+}
+
+// WithConsumerTag will configure a Consumer with the given consumer tag.
+func WithConsumerTag(tag string) ConsumerOption {
+	return consumerOption(func(options *consumerOptions) error {
+		options.tag = tag
+		return nil
+	})
+}
+
+// WithConsumerPrefetch will configure a Consumer with the given prefetch count.
+func WithConsumerPrefetch(prefetch int) ConsumerOption {
+	return consumerOption(func(options *consumerOptions) error {
+		if prefetch < 0 {
+			return ErrInvalidConsumerPrefetch
+		}
+		options.prefetch = prefetch
+		return nil
+	})
+}
+
+// WithConsumerMiddlewares will configure a Consumer with the given middlewares.
+// The first middleware is the outermost one, i.e. it's the first to handle a delivery.
+func WithConsumerMiddlewares(middlewares ...Middleware) ConsumerOption {
+	return consumerOption(func(options *consumerOptions) error {
+		for _, middleware := range middlewares {
+			if middleware == nil {
+				return ErrMiddlewareRequired
+			}
+		}
+		options.middlewares = append(options.middlewares, middlewares...)
+		return nil
+	})
+}
+
+// WithConsumerTracer will configure a Consumer to extract the trace context of deliveries using the given tracer.
+func WithConsumerTracer(tracer Tracer) ConsumerOption {
+	return consumerOption(func(options *consumerOptions) error {
+		if tracer == nil {
+			return ErrTracerRequired
+		}
+		options.tracer = tracer
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+package amqpx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestConsumer(t *testing.T) {
+	is := NewRunner(t)
+
+	client, err := NewClient()
+	is.NoError(err)
+	is.NotNil(client)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	topic := "random.consumer"
+	is.NoError(DeclareQueue(client, topic))
+
+	publisher, err := amqpx.NewPublisher[Event](client, warpExchange, topic)
+	is.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	received := make(chan Event, 1)
+	consumer, err := amqpx.NewConsumer(client, topic,
+		func(ctx context.Context, event Event, delivery amqp.Delivery) error {
+			received <- event
+			return nil
+		},
+	)
+	is.NoError(err)
+	is.Equal(topic, consumer.Queue())
+
+	go func() {
+		thr := consumer.Consume(ctx)
+		_ = thr
+	}()
+
+	is.NoError(publisher.Publish(ctx, Event{Message: "hello"}))
+
+	select {
+	case event := <-received:
+		is.Equal("hello", event.Message)
+	case <-ctx.Done():
+		is.NoError(ctx.Err())
+	}
+}
+
+func TestConsumer_DecodeError(t *testing.T) {
+	is := NewRunner(t)
+
+	client, err := NewClient()
+	is.NoError(err)
+	is.NotNil(client)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	topic := "random.consumer.decode"
+	is.NoError(DeclareQueue(client, topic))
+
+	publisher, err := amqpx.NewPublisher[string](client, warpExchange, topic)
+	is.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	failures := make(chan error, 1)
+	consumer, err := amqpx.NewConsumer(client, topic,
+		func(ctx context.Context, event Event, delivery amqp.Delivery) error {
+			return nil
+		},
+		amqpx.WithConsumerErrorHandler(func(delivery amqp.Delivery, err error) {
+			thr := delivery.Nack(false, false)
+			_ = thr
+			failures <- err
+		}),
+	)
+	is.NoError(err)
+
+	go func() {
+		thr := consumer.Consume(ctx)
+		_ = thr
+	}()
+
+	is.NoError(publisher.Publish(ctx, "not an event"))
+
+	select {
+	case err := <-failures:
+		is.Contains(err.Error(), amqpx.ErrMessageCannotDecodeMessage)
+	case <-ctx.Done():
+		is.NoError(ctx.Err())
+	}
+}
+
+func TestConsumer_Required(t *testing.T) {
+	is := NewRunner(t)
+
+	consumer, err := amqpx.NewConsumer[Event](nil, "random.consumer", nil)
+	is.Error(err)
+	is.Nil(consumer)
+	is.Equal(amqpx.ErrClientRequired, errors.Cause(err))
+}
@@ -2,10 +2,11 @@ package amqpx
 
 import (
 	"net"
+	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // Dialer default configuration.
@@ -19,16 +20,51 @@ var (
 type Dialer interface {
 	Timeout() time.Duration
 	Heartbeat() time.Duration
-	dial(id int) (*amqp.Connection, error)
+	dial(id int) (driverConnection, error)
+	uri(id int) string
+	options() dialerOptions
+	transport() transport
 }
 
-func dialer(timeout time.Duration) func(network string, address string) (net.Conn, error) {
+// transport opens a network connection to a broker address.
+type transport func(network string, address string) (net.Conn, error)
+
+// options returns the dialer configuration.
+func (e dialerOptions) options() dialerOptions {
+	return e
+}
+
+// transport returns the transport used to reach the brokers.
+func (e dialerOptions) transport() transport {
+	return dialer(e.timeout)
+}
+
+// open opens a new connection on given broker uri.
+func (e dialerOptions) open(uri string) (driverConnection, error) {
+	return e.openWith(uri, e.transport())
+}
+
+// openWith opens a new connection on given broker uri, using given transport.
+// The TLS configuration is cloned, since the driver sets its server name from the uri.
+func (e dialerOptions) openWith(uri string, transport transport) (driverConnection, error) {
+	connection, err := amqp.DialConfig(uri, amqp.Config{
+		Dial:            transport,
+		Heartbeat:       e.heartbeat,
+		TLSClientConfig: e.tls.Clone(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return amqpConnection{Connection: connection}, nil
+}
+
+func dialer(timeout time.Duration) transport {
 	return func(network string, address string) (net.Conn, error) {
 
 		// Dial a remote address with a timeout.
 		conn, err := net.DialTimeout(network, address, timeout)
 		if err != nil {
-			return nil, errors.Wrap(err, ErrMessageDialTimeout)
+			return nil, errors.Wrap(err, dialErrorMessage(err))
 		}
 
 		// Heartbeating hasn't started yet, don't stall forever to receive packets from server.
@@ -46,3 +82,11 @@ func dialer(timeout time.Duration) func(network string, address string) (net.Con
 		return conn, nil
 	}
 }
+
+// dialErrorMessage returns the message describing given dial error: a refused connection, or a timeout.
+func dialErrorMessage(err error) string {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrMessageDialRefused
+	}
+	return ErrMessageDialTimeout
+}
@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
 )
 
 // ClusterDialer is a Dialer that uses a cluster of broker.
@@ -45,13 +44,14 @@ func (e clusterDialer) Heartbeat() time.Duration {
 }
 
 // dial implements Dialer interface.
-func (e clusterDialer) dial(id int) (*amqp.Connection, error) {
+func (e clusterDialer) dial(id int) (driverConnection, error) {
+	return e.open(e.uri(id))
+}
+
+// uri implements Dialer interface.
+func (e clusterDialer) uri(id int) string {
 	idx := (id) % len(e.list)
-	uri := e.list[idx]
-	return amqp.DialConfig(uri, amqp.Config{
-		Dial:      dialer(e.timeout),
-		Heartbeat: e.heartbeat,
-	})
+	return e.list[idx]
 }
 
 var _ Dialer = (*clusterDialer)(nil)
@@ -5,7 +5,7 @@ import (
 
 	"github.com/pkg/errors"
 
-	"github.com/ulule/amqpx"
+	"github.com/ulule/amqpx/v3"
 )
 
 func TestDialer_Cluster(t *testing.T) {
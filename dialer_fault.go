@@ -0,0 +1,405 @@
+package amqpx
+
+import (
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FaultDialer is a Dialer which injects network faults in the connections opened by another Dialer.
+// It's intended for chaos testing: faults can be scripted from a test while a Client is running.
+//
+// Brokers are identified by their address, as "host:port", like in their URI.
+type FaultDialer struct {
+	dialer      Dialer
+	mutex       sync.Mutex
+	refused     bool
+	refuseNext  int
+	latency     time.Duration
+	dropBytes   int64
+	dropMin     time.Duration
+	dropMax     time.Duration
+	partitions  map[string]struct{}
+	connections map[*faultConn]struct{}
+	dials       int
+}
+
+// NewFaultDialer returns a FaultDialer which wraps given Dialer.
+// No fault is injected until one is configured.
+func NewFaultDialer(dialer Dialer) (*FaultDialer, error) {
+	if dialer == nil {
+		return nil, errors.Wrap(ErrDialerRequired, ErrMessageCannotCreateDialer)
+	}
+
+	return &FaultDialer{
+		dialer:      dialer,
+		partitions:  map[string]struct{}{},
+		connections: map[*faultConn]struct{}{},
+	}, nil
+}
+
+// Timeout implements Dialer interface.
+func (e *FaultDialer) Timeout() time.Duration {
+	return e.dialer.Timeout()
+}
+
+// Heartbeat implements Dialer interface.
+func (e *FaultDialer) Heartbeat() time.Duration {
+	return e.dialer.Heartbeat()
+}
+
+// dial implements Dialer interface.
+func (e *FaultDialer) dial(id int) (driverConnection, error) {
+	return e.options().openWith(e.uri(id), e.transport())
+}
+
+// uri implements Dialer interface.
+func (e *FaultDialer) uri(id int) string {
+	return e.dialer.uri(id)
+}
+
+// options implements Dialer interface.
+func (e *FaultDialer) options() dialerOptions {
+	return e.dialer.options()
+}
+
+// transport implements Dialer interface.
+// The faults are injected in the transport of the wrapped Dialer, so wrapped faults are kept.
+func (e *FaultDialer) transport() transport {
+	return e.inject(e.dialer.transport(), e.Timeout())
+}
+
+// Refuse configures if every dial is refused, like a broker which isn't listening.
+func (e *FaultDialer) Refuse(refused bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.refused = refused
+}
+
+// RefuseNext refuses the given number of upcoming dials.
+func (e *FaultDialer) RefuseNext(count int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.refuseNext = count
+}
+
+// SetLatency adds given latency to every dial.
+// A latency which reaches the dialer timeout makes dials time out.
+func (e *FaultDialer) SetLatency(latency time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.latency = latency
+}
+
+// DropAfterBytes drops new connections once they have read or written given number of bytes.
+// A zero limit disables this fault.
+func (e *FaultDialer) DropAfterBytes(limit int64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.dropBytes = limit
+}
+
+// DropAfter drops new connections after a random duration between given bounds.
+// A zero maximum disables this fault.
+func (e *FaultDialer) DropAfter(min time.Duration, max time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if min > max {
+		min, max = max, min
+	}
+
+	e.dropMin = min
+	e.dropMax = max
+}
+
+// Partition isolates given broker addresses: their connections are dropped, and dials are refused
+// until they are healed.
+func (e *FaultDialer) Partition(addresses ...string) {
+	e.mutex.Lock()
+	for _, address := range addresses {
+		e.partitions[address] = struct{}{}
+	}
+	connections := e.match(func(conn *faultConn) bool {
+		_, ok := e.partitions[conn.address]
+		return ok
+	})
+	e.mutex.Unlock()
+
+	drop(connections)
+}
+
+// Heal removes the partition of given broker addresses, or of every broker if none is given.
+func (e *FaultDialer) Heal(addresses ...string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(addresses) == 0 {
+		e.partitions = map[string]struct{}{}
+		return
+	}
+
+	for _, address := range addresses {
+		delete(e.partitions, address)
+	}
+}
+
+// DropConnections drops every established connection.
+func (e *FaultDialer) DropConnections() {
+	e.mutex.Lock()
+	connections := e.match(func(conn *faultConn) bool {
+		return true
+	})
+	e.mutex.Unlock()
+
+	drop(connections)
+}
+
+// Reset removes every configured fault. Established connections are kept.
+func (e *FaultDialer) Reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.refused = false
+	e.refuseNext = 0
+	e.latency = 0
+	e.dropBytes = 0
+	e.dropMin = 0
+	e.dropMax = 0
+	e.partitions = map[string]struct{}{}
+}
+
+// Dials returns the number of dials attempted, including the refused ones.
+func (e *FaultDialer) Dials() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.dials
+}
+
+// Connections returns the number of established connections.
+func (e *FaultDialer) Connections() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.connections)
+}
+
+// inject returns a transport which injects the configured faults in the connections of given transport.
+// The latency is spent within the dialer timeout: the wrapped transport and the handshake only get what remains.
+func (e *FaultDialer) inject(next transport, timeout time.Duration) transport {
+	return func(network string, address string) (net.Conn, error) {
+		start := time.Now()
+		latency, refused := e.admit(address)
+
+		if timeout > 0 && latency >= timeout {
+			time.Sleep(timeout)
+			return nil, dialTimeoutError(network)
+		}
+		time.Sleep(latency)
+
+		if refused {
+			return nil, errors.Wrap(&net.OpError{
+				Op:  "dial",
+				Net: network,
+				Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+			}, ErrMessageDialRefused)
+		}
+
+		deadline := time.Time{}
+		if timeout > 0 {
+			deadline = start.Add(timeout)
+		}
+
+		conn, err := dialUntil(next, network, address, deadline)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.track(address, conn), nil
+	}
+}
+
+// dialUntil calls given transport, and fails if it doesn't return before given deadline, if any.
+// The deadline of the connection is also set to it, so it bounds the handshake too.
+func dialUntil(next transport, network string, address string, deadline time.Time) (net.Conn, error) {
+	if deadline.IsZero() {
+		return next(network, address)
+	}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	results := make(chan result, 1)
+	go func() {
+		conn, err := next(network, address)
+		results <- result{conn: conn, err: err}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case result := <-results:
+		if result.err != nil {
+			return nil, result.err
+		}
+
+		err := result.conn.SetDeadline(deadline)
+		if err != nil {
+			thr := result.conn.Close()
+			_ = thr
+			return nil, errors.Wrap(err, ErrMessageReadTimeout)
+		}
+
+		return result.conn, nil
+
+	case <-timer.C:
+		// The connection opened too late is closed.
+		go func() {
+			result := <-results
+			if result.conn != nil {
+				thr := result.conn.Close()
+				_ = thr
+			}
+		}()
+
+		return nil, dialTimeoutError(network)
+	}
+}
+
+// dialTimeoutError returns the error of a dial which has reached the dialer timeout.
+func dialTimeoutError(network string) error {
+	return errors.Wrap(&net.OpError{
+		Op:  "dial",
+		Net: network,
+		Err: os.ErrDeadlineExceeded,
+	}, ErrMessageDialTimeout)
+}
+
+// admit records a dial on given address, and returns its latency and if it's refused.
+func (e *FaultDialer) admit(address string) (time.Duration, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.dials++
+
+	_, partitioned := e.partitions[address]
+	refused := e.refused || partitioned || e.refuseNext > 0
+	if e.refuseNext > 0 {
+		e.refuseNext--
+	}
+
+	return e.latency, refused
+}
+
+// track wraps given connection with the configured drop faults.
+func (e *FaultDialer) track(address string, conn net.Conn) net.Conn {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	wrapper := &faultConn{
+		Conn:    conn,
+		dialer:  e,
+		address: address,
+		limit:   e.dropBytes,
+	}
+	e.connections[wrapper] = struct{}{}
+
+	if e.dropMax > 0 {
+		delay := e.dropMin + time.Duration(rand.Int63n(int64(e.dropMax-e.dropMin)+1))
+		time.AfterFunc(delay, wrapper.drop)
+	}
+
+	return wrapper
+}
+
+// untrack forgets given connection.
+func (e *FaultDialer) untrack(conn *faultConn) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.connections, conn)
+}
+
+// match returns the established connections which satisfy given predicate.
+// It must be called with the mutex held.
+func (e *FaultDialer) match(predicate func(conn *faultConn) bool) []*faultConn {
+	connections := []*faultConn{}
+	for conn := range e.connections {
+		if predicate(conn) {
+			connections = append(connections, conn)
+		}
+	}
+
+	return connections
+}
+
+// drop drops given connections.
+func drop(connections []*faultConn) {
+	for _, conn := range connections {
+		conn.drop()
+	}
+}
+
+var _ Dialer = (*FaultDialer)(nil)
+
+// faultConn is a connection opened by a FaultDialer, which can be dropped at any time.
+type faultConn struct {
+	net.Conn
+	dialer  *FaultDialer
+	address string
+	limit   int64
+	count   atomic.Int64
+	once    sync.Once
+}
+
+// Read implements net.Conn interface.
+func (e *faultConn) Read(b []byte) (int, error) {
+	n, err := e.Conn.Read(b)
+	e.consume(n)
+	return n, err
+}
+
+// Write implements net.Conn interface.
+func (e *faultConn) Write(b []byte) (int, error) {
+	n, err := e.Conn.Write(b)
+	e.consume(n)
+	return n, err
+}
+
+// Close implements net.Conn interface.
+func (e *faultConn) Close() error {
+	err := e.Conn.Close()
+	e.drop()
+	return err
+}
+
+// consume counts given transferred bytes, and drops the connection once its limit is reached.
+func (e *faultConn) consume(n int) {
+	if e.limit > 0 && e.count.Add(int64(n)) >= e.limit {
+		e.drop()
+	}
+}
+
+// drop closes the underlying socket abruptly, and forgets the connection.
+func (e *faultConn) drop() {
+	e.once.Do(func() {
+		thr := e.Conn.Close()
+		_ = thr
+
+		e.dialer.untrack(e)
+	})
+}
@@ -0,0 +1,255 @@
+package amqpx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func NewFaultServer(t *testing.T, broker *amqpxtest.Broker) *amqpxtest.Server {
+	t.Helper()
+
+	server, err := amqpxtest.NewServer(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		thr := server.Kill()
+		_ = thr
+	})
+
+	return server
+}
+
+func TestDialer_Fault_DialerRequired(t *testing.T) {
+	is := NewRunner(t)
+
+	dialer, err := amqpx.NewFaultDialer(nil)
+	is.Error(err)
+	is.Nil(dialer)
+	is.Equal(amqpx.ErrDialerRequired, errors.Cause(err))
+}
+
+func TestDialer_Fault_Refuse(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewFaultServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	is.Equal(simple.Timeout(), dialer.Timeout())
+	is.Equal(simple.Heartbeat(), dialer.Heartbeat())
+
+	dialer.Refuse(true)
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+	is.True(amqpx.IsRetryable(err))
+	is.Contains(err.Error(), amqpx.ErrMessageDialRefused)
+	is.False(strings.Contains(err.Error(), amqpx.ErrMessageDialTimeout))
+	is.Equal(0, server.Connections())
+
+	dialer.Reset()
+	dialer.RefuseNext(1)
+	_, err = amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.NoError(err)
+	is.NoError(client.Close())
+	is.Equal(3, dialer.Dials())
+}
+
+func TestDialer_Fault_Nested(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewFaultServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	inner, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	outer, err := amqpx.NewFaultDialer(inner)
+	is.NoError(err)
+
+	// Faults of the wrapped dialer are kept.
+	inner.Refuse(true)
+	client, err := amqpx.New(outer, amqpx.WithCapacity(1))
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+	is.Equal(1, outer.Dials())
+	is.Equal(1, inner.Dials())
+
+	inner.Reset()
+	client, err = amqpx.New(outer, amqpx.WithCapacity(1))
+	is.NoError(err)
+	is.Equal(1, outer.Connections())
+	is.Equal(1, inner.Connections())
+
+	inner.DropConnections()
+	is.Eventually(func() bool {
+		return inner.Dials() == 3 && outer.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	is.NoError(client.Close())
+}
+
+func TestDialer_Fault_Latency(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewFaultServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI(), amqpx.WithDialerTimeout(200*time.Millisecond))
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+
+	dialer.SetLatency(100 * time.Millisecond)
+	start := time.Now()
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.NoError(err)
+	is.True(time.Since(start) >= 100*time.Millisecond)
+	is.NoError(client.Close())
+
+	dialer.SetLatency(time.Second)
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrTimeout))
+	is.True(amqpx.IsRetryable(err))
+}
+
+func TestDialer_Fault_LatencyWithinTimeout(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewFaultServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI(), amqpx.WithDialerTimeout(300*time.Millisecond))
+	is.NoError(err)
+
+	inner, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	outer, err := amqpx.NewFaultDialer(inner)
+	is.NoError(err)
+
+	// Each latency is below the timeout, but not their sum: the dial times out within the timeout.
+	inner.SetLatency(200 * time.Millisecond)
+	outer.SetLatency(200 * time.Millisecond)
+	start := time.Now()
+	client, err := amqpx.New(outer, amqpx.WithoutConnectionsPool())
+	elapsed := time.Since(start)
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrTimeout))
+	is.True(elapsed < 400*time.Millisecond, elapsed.String())
+
+	is.Eventually(func() bool {
+		return inner.Connections() == 0 && server.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestDialer_Fault_DropAfterBytes(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewFaultServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+	is.Equal(1, dialer.Connections())
+
+	dialer.DropConnections()
+	is.Eventually(func() bool {
+		return dialer.Dials() == 2 && dialer.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	dialer.DropAfterBytes(1)
+	dialer.DropConnections()
+	is.Eventually(func() bool {
+		return dialer.Dials() >= 4
+	}, 5*time.Second, 20*time.Millisecond)
+
+	dialer.Reset()
+	is.Eventually(func() bool {
+		return server.Connections() == 1 && dialer.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+}
+
+func TestDialer_Fault_DropAfter(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewFaultServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	dialer.DropAfter(50*time.Millisecond, 100*time.Millisecond)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	is.Eventually(func() bool {
+		return dialer.Dials() >= 4
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestDialer_Fault_Partition(t *testing.T) {
+	is := NewRunner(t)
+
+	broker := amqpxtest.NewBroker()
+	server := NewFaultServer(t, broker)
+	other := NewFaultServer(t, broker)
+
+	cluster, err := amqpx.ClusterDialer([]string{server.URI(), other.URI()})
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(cluster)
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(4))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+	is.Equal(2, server.Connections())
+	is.Equal(2, other.Connections())
+
+	dialer.Partition(server.Addr())
+	is.Eventually(func() bool {
+		return server.Connections() == 0 && dialer.Connections() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		channel, err := client.Channel()
+		is.NoError(err)
+		is.NoError(channel.Close())
+	}
+
+	dialer.Heal(server.Addr())
+	is.Eventually(func() bool {
+		return server.Connections() == 2 && dialer.Connections() == 4
+	}, 5*time.Second, 20*time.Millisecond)
+}
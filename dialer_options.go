@@ -1,6 +1,9 @@
 package amqpx
 
-import "time"
+import (
+	"crypto/tls"
+	"time"
+)
 
 // DialerOption is used to define dialer configuration.
 type DialerOption interface {
@@ -16,6 +19,7 @@ func (o dialerOption) apply(instance *dialerOptions) error {
 type dialerOptions struct {
 	timeout   time.Duration
 	heartbeat time.Duration
+	tls       *tls.Config
 }
 
 func newDialerOptions() dialerOptions {
@@ -46,3 +50,14 @@ func WithDialerHeartbeat(heartbeat time.Duration) DialerOption {
 		return nil
 	})
 }
+
+// WithDialerTLSConfig will configure a Dialer with the given TLS configuration, used for amqps URIs.
+func WithDialerTLSConfig(config *tls.Config) DialerOption {
+	return dialerOption(func(options *dialerOptions) error {
+		if config == nil {
+			return ErrTLSConfigRequired
+		}
+		options.tls = config
+		return nil
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
 )
 
 // SimpleDialer gives a Dialer that uses a simple broker.
@@ -23,7 +22,7 @@ func SimpleDialer(uri string, options ...DialerOption) (Dialer, error) {
 
 	dialer := &simpleDialer{
 		dialerOptions: opts,
-		uri:           uri,
+		broker:        uri,
 	}
 
 	return dialer, nil
@@ -31,7 +30,7 @@ func SimpleDialer(uri string, options ...DialerOption) (Dialer, error) {
 
 type simpleDialer struct {
 	dialerOptions
-	uri string
+	broker string
 }
 
 // Timeout implements Dialer interface.
@@ -44,11 +43,14 @@ func (e simpleDialer) Heartbeat() time.Duration {
 	return e.heartbeat
 }
 
-func (e simpleDialer) dial(id int) (*amqp.Connection, error) {
-	return amqp.DialConfig(e.uri, amqp.Config{
-		Dial:      dialer(e.timeout),
-		Heartbeat: e.heartbeat,
-	})
+// dial implements Dialer interface.
+func (e simpleDialer) dial(id int) (driverConnection, error) {
+	return e.open(e.uri(id))
+}
+
+// uri implements Dialer interface.
+func (e simpleDialer) uri(id int) string {
+	return e.broker
 }
 
 var _ Dialer = (*simpleDialer)(nil)
@@ -1,11 +1,14 @@
 package amqpx_test
 
 import (
+	"crypto/tls"
+	"net"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
 
-	"github.com/ulule/amqpx"
+	"github.com/ulule/amqpx/v3"
 )
 
 func TestDialer_Simple(t *testing.T) {
@@ -52,3 +55,36 @@ func TestDialer_Simple_WithDialerHeartbeat(t *testing.T) {
 	is.NotNil(dialer)
 	is.Equal(dialerHeartbeat, dialer.Heartbeat())
 }
+
+func TestDialer_Simple_WithDialerTLSConfig(t *testing.T) {
+	is := NewRunner(t)
+
+	dialer, err := amqpx.SimpleDialer(brokerURI, amqpx.WithDialerTLSConfig(nil))
+	is.Error(err)
+	is.Nil(dialer)
+	is.Equal(amqpx.ErrTLSConfigRequired, errors.Cause(err))
+
+	dialer, err = amqpx.SimpleDialer(brokerURI, amqpx.WithDialerTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	is.NoError(err)
+	is.NotNil(dialer)
+}
+
+func TestDialer_Simple_ClosedPort(t *testing.T) {
+	is := NewRunner(t)
+
+	// A listener is closed right away, so nothing listens on its port anymore.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoError(err)
+	address := listener.Addr().String()
+	is.NoError(listener.Close())
+
+	dialer, err := amqpx.SimpleDialer("amqp://guest:guest@" + address + "/")
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithoutConnectionsPool())
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+	is.Contains(err.Error(), amqpx.ErrMessageDialRefused)
+	is.False(strings.Contains(err.Error(), amqpx.ErrMessageDialTimeout))
+}
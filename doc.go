@@ -1,4 +1,4 @@
 // Package amqpx provides extensions for Go AMQP library.
 //
-// It uses https://github.com/streadway/amqp as dependency.
+// It uses https://github.com/rabbitmq/amqp091-go as dependency.
 package amqpx
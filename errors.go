@@ -2,12 +2,40 @@ package amqpx
 
 import (
 	"fmt"
+	"strings"
 )
 
 var (
 	// ErrInvalidConnectionsPoolCapacity occurs when the defined connections pool's capacity is invalid .
 	ErrInvalidConnectionsPoolCapacity = fmt.Errorf("invalid connections pool capacity")
 
+	// ErrInvalidMinConnections occurs when the defined minimum number of connections is invalid.
+	ErrInvalidMinConnections = fmt.Errorf("invalid minimum number of connections")
+
+	// ErrInvalidStartupParallelism occurs when the defined number of connections dialed concurrently is invalid.
+	ErrInvalidStartupParallelism = fmt.Errorf("invalid startup parallelism")
+
+	// ErrInvalidStartupTimeout occurs when the defined startup timeout is invalid.
+	ErrInvalidStartupTimeout = fmt.Errorf("invalid startup timeout")
+
+	// ErrStartupTimeout occurs when the connections are not opened before the startup timeout.
+	ErrStartupTimeout = fmt.Errorf("startup timeout is reached")
+
+	// ErrInvalidDrainTimeout occurs when the defined drain timeout is invalid.
+	ErrInvalidDrainTimeout = fmt.Errorf("invalid drain timeout")
+
+	// ErrInvalidConnectionMaxLifetime occurs when the defined connection max lifetime or its jitter is invalid.
+	ErrInvalidConnectionMaxLifetime = fmt.Errorf("invalid connection max lifetime")
+
+	// ErrInvalidAutoscalingPolicy occurs when the defined autoscaling policy is invalid.
+	ErrInvalidAutoscalingPolicy = fmt.Errorf("invalid autoscaling policy")
+
+	// ErrInvalidSelectionStrategy occurs when the defined connections pool's selection strategy is invalid.
+	ErrInvalidSelectionStrategy = fmt.Errorf("invalid connections pool selection strategy")
+
+	// ErrInvalidReconnectBackoff occurs when the defined reconnect backoff is invalid.
+	ErrInvalidReconnectBackoff = fmt.Errorf("invalid reconnect backoff")
+
 	// ErrInvalidDialerTimeout occurs when the defined dialer timeout is invalid.
 	ErrInvalidDialerTimeout = fmt.Errorf("invalid dialer timeout")
 
@@ -20,25 +48,198 @@ var (
 	// ErrClientClosed occurs when operating on a closed client.
 	ErrClientClosed = fmt.Errorf("client is closed")
 
+	// ErrTLSConfigRequired occurs when given TLS configuration is empty.
+	ErrTLSConfigRequired = fmt.Errorf("a TLS configuration is required")
+
+	// ErrDialerRequired occurs when given dialer is empty.
+	ErrDialerRequired = fmt.Errorf("a dialer instance is required")
+
 	// ErrBrokerURIRequired occurs when a dialer has no broker URI.
 	ErrBrokerURIRequired = fmt.Errorf("broker URI is required")
 
+	// ErrInvalidBrokerURI occurs when a broker URI cannot be parsed.
+	ErrInvalidBrokerURI = fmt.Errorf("invalid broker URI")
+
+	// ErrClusterModeRequired occurs when a configuration defines several broker URIs without the cluster mode.
+	ErrClusterModeRequired = fmt.Errorf("cluster mode is required for several broker URIs")
+
+	// ErrInvalidConfigDuration occurs when a configuration duration cannot be parsed.
+	ErrInvalidConfigDuration = fmt.Errorf("invalid duration")
+
+	// ErrInvalidTLSKeyPair occurs when a TLS client certificate is defined without its key, or the other way around.
+	ErrInvalidTLSKeyPair = fmt.Errorf("TLS certificate and key must be defined together")
+
+	// ErrInvalidTLSCA occurs when the TLS certificate authorities cannot be parsed.
+	ErrInvalidTLSCA = fmt.Errorf("invalid TLS certificate authorities")
+
+	// ErrConnectionsPoolRequired occurs when a pool of a Split client is configured without a connections pool.
+	ErrConnectionsPoolRequired = fmt.Errorf("a connections pool is required")
+
 	// ErrObserverRequired occurs when given observer is empty.
 	ErrObserverRequired = fmt.Errorf("an observer instance is required")
 
 	// ErrLoggerRequired occurs when given logger is not set.
 	ErrLoggerRequired = fmt.Errorf("a logger instance is required")
+
+	// ErrLoggerOutputRequired occurs when given logger output is not set.
+	ErrLoggerOutputRequired = fmt.Errorf("a logger output is required")
+
+	// ErrInvalidLoggerLevel occurs when the defined logger level is invalid.
+	ErrInvalidLoggerLevel = fmt.Errorf("invalid logger level")
+
+	// ErrInvalidLoggerFormat occurs when the defined logger format is invalid.
+	ErrInvalidLoggerFormat = fmt.Errorf("invalid logger format")
+
+	// ErrInvalidLoggerPrecision occurs when the defined logger timestamp precision is invalid.
+	ErrInvalidLoggerPrecision = fmt.Errorf("invalid logger precision")
+
+	// ErrInvalidLoggerDeduplicationWindow occurs when the defined logger deduplication window is invalid.
+	ErrInvalidLoggerDeduplicationWindow = fmt.Errorf("invalid logger deduplication window")
+
+	// ErrMetricsRequired occurs when given metrics collector is not set.
+	ErrMetricsRequired = fmt.Errorf("a metrics instance is required")
+
+	// ErrExpvarNameConflict occurs when an expvar variable which is not an expvar.Map is published with given name.
+	ErrExpvarNameConflict = fmt.Errorf("expvar name is already published with another type")
+
+	// ErrClientRequired occurs when given client is empty.
+	ErrClientRequired = fmt.Errorf("a client instance is required")
+
+	// ErrCodecRequired occurs when given codec is empty.
+	ErrCodecRequired = fmt.Errorf("a codec instance is required")
+
+	// ErrHandlerRequired occurs when given handler is empty.
+	ErrHandlerRequired = fmt.Errorf("a handler is required")
+
+	// ErrTracerRequired occurs when given tracer is empty.
+	ErrTracerRequired = fmt.Errorf("a tracer instance is required")
+
+	// ErrMiddlewareRequired occurs when given middleware is empty.
+	ErrMiddlewareRequired = fmt.Errorf("a middleware is required")
+
+	// ErrErrorHandlerRequired occurs when given error handler is empty.
+	ErrErrorHandlerRequired = fmt.Errorf("an error handler is required")
+
+	// ErrInvalidConsumerPrefetch occurs when the defined consumer prefetch count is invalid.
+	ErrInvalidConsumerPrefetch = fmt.Errorf("invalid consumer prefetch")
+
+	// ErrDeliveriesClosed occurs when the deliveries of a consumer are closed by the server.
+	ErrDeliveriesClosed = fmt.Errorf("deliveries are closed")
+
+	// ErrInterceptorRequired occurs when given publish interceptor is empty.
+	ErrInterceptorRequired = fmt.Errorf("a publish interceptor is required")
+
+	// ErrMessageTooLarge occurs when a message body exceeds the allowed size.
+	ErrMessageTooLarge = fmt.Errorf("message is too large")
+
+	// ErrPublishNacked occurs when a published message is rejected by the broker.
+	ErrPublishNacked = fmt.Errorf("message was not confirmed by broker")
+
+	// ErrPublishUnconfirmed occurs when the channel is closed before a published message is confirmed.
+	ErrPublishUnconfirmed = fmt.Errorf("channel was closed before message was confirmed")
+
+	// ErrHandlerPanic occurs when a handler has panicked.
+	ErrHandlerPanic = fmt.Errorf("handler has panicked")
 )
 
+// ConnectionCloseError occurs when a connection of a client cannot be closed.
+type ConnectionCloseError struct {
+	// Slot is the slot of the connection in the connections pool, or zero without a pool.
+	Slot int
+	// Address is the local address of the connection.
+	Address string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error interface.
+func (e *ConnectionCloseError) Error() string {
+	return fmt.Sprintf("%s (slot: %d, address: %s): %s", ErrMessageCannotCloseConnection, e.Slot, e.Address, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConnectionCloseError) Unwrap() error {
+	return e.Err
+}
+
+// PoolError occurs in one of the connections pools of a Split client.
+type PoolError struct {
+	// Pool is the name of the connections pool, such as PoolPublish or PoolConsume.
+	Pool string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error interface.
+func (e *PoolError) Error() string {
+	return fmt.Sprintf("%s pool: %s", e.Pool, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PoolError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error, so errors.Cause can find its root cause.
+func (e *PoolError) Cause() error {
+	return e.Err
+}
+
+// errorList aggregates several errors.
+type errorList []error
+
+// add appends given error, unless it's nil.
+func (e *errorList) add(err error) {
+	if err != nil {
+		*e = append(*e, err)
+	}
+}
+
+// err returns the aggregated errors, or nil if there are none.
+func (e errorList) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+// Error implements error interface.
+func (e errorList) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d errors: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the aggregated errors, so errors.Is and errors.As can match any of them.
+func (e errorList) Unwrap() []error {
+	return e
+}
+
 // Error Messages
 const (
 	ErrMessageCannotCreateDialer    = "cannot create a new dialer"
 	ErrMessageCannotCreateClient    = "cannot create a new client"
+	ErrMessageCannotCreateLogger    = "cannot create a new logger"
 	ErrMessageCannotOpenConnection  = "cannot open a new connection"
 	ErrMessageCannotOpenChannel     = "cannot open a new channel"
+	ErrMessageCannotResizePool      = "cannot resize connections pool"
 	ErrMessageCannotCloseConnection = "cannot close connection"
 	ErrMessageCannotCloseChannel    = "cannot close channel"
 	ErrMessageDialTimeout           = "dialing remote address has timeout"
+	ErrMessageDialRefused           = "dialing remote address was refused"
 	ErrMessageReadTimeout           = "reading on socket has timeout"
 	ErrMessageWriteTimeout          = "writing on socket has timeout"
+	ErrMessageCannotCreatePublisher = "cannot create a new publisher"
+	ErrMessageCannotCreateConsumer  = "cannot create a new consumer"
+	ErrMessageCannotPublishMessage  = "cannot publish message"
+	ErrMessageCannotConsumeQueue    = "cannot consume queue"
+	ErrMessageCannotEncodeMessage   = "cannot encode message"
+	ErrMessageCannotDecodeMessage   = "cannot decode message"
+	ErrMessageCannotAckMessage      = "cannot acknowledge message"
 )
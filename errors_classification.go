@@ -0,0 +1,136 @@
+package amqpx
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	// ErrAccessRefused occurs when the broker refuses the credentials or the access to a resource.
+	ErrAccessRefused = fmt.Errorf("access refused")
+
+	// ErrVhostNotFound occurs when the broker has no such virtual host.
+	ErrVhostNotFound = fmt.Errorf("vhost not found")
+
+	// ErrConnectionRefused occurs when the broker refuses a TCP connection.
+	ErrConnectionRefused = fmt.Errorf("connection refused")
+
+	// ErrConnectionClosed occurs when operating on a closed connection or channel.
+	ErrConnectionClosed = fmt.Errorf("connection is closed")
+
+	// ErrChannelMax occurs when a connection has no channel available.
+	ErrChannelMax = fmt.Errorf("channel max reached")
+
+	// ErrTimeout occurs when a network operation has timeout.
+	ErrTimeout = fmt.Errorf("network timeout")
+
+	// ErrNetwork occurs when a network operation has failed.
+	ErrNetwork = fmt.Errorf("network error")
+
+	// ErrBroker occurs when the broker replies with an error.
+	ErrBroker = fmt.Errorf("broker error")
+)
+
+// Error is a classified error.
+// It supports errors.Is with its Kind, such as ErrAccessRefused, and errors.As with its underlying error.
+type Error struct {
+	// Kind is the class of error, such as ErrAccessRefused or ErrConnectionRefused.
+	Kind error
+	// Code is the AMQP reply code, or zero if there is none.
+	Code int
+	// Err is the underlying error.
+	Err error
+
+	retryable bool
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
+
+// Unwrap returns both the error kind and the underlying error.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Retryable returns if the operation could succeed if it's retried.
+func (e *Error) Retryable() bool {
+	return e.retryable
+}
+
+// IsRetryable returns if the operation which has returned given error could succeed if it's retried.
+// Only errors classified as permanent, such as an authentication failure or a missing vhost, are not retryable,
+// with the ones of a closed client: unclassified errors, such as TLS failures, are retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrClientClosed) {
+		return false
+	}
+
+	var classified *Error
+	if !errors.As(classifyError(err), &classified) {
+		return true
+	}
+
+	return classified.Retryable()
+}
+
+// classifyError returns given error as an *Error if it can be classified, otherwise it returns given error.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var reply *amqp.Error
+	if errors.As(err, &reply) {
+		return classifyReplyError(err, reply)
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return &Error{Kind: ErrConnectionRefused, Err: err, retryable: true}
+	}
+
+	var network net.Error
+	if errors.As(err, &network) && network.Timeout() {
+		return &Error{Kind: ErrTimeout, Err: err, retryable: true}
+	}
+
+	if network != nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &Error{Kind: ErrNetwork, Err: err, retryable: true}
+	}
+
+	return err
+}
+
+// classifyReplyError returns given error as an *Error using its amqp reply.
+func classifyReplyError(err error, reply *amqp.Error) error {
+	switch {
+	case reply == amqp.ErrClosed:
+		return &Error{Kind: ErrConnectionClosed, Code: reply.Code, Err: err, retryable: true}
+
+	case reply == amqp.ErrChannelMax:
+		return &Error{Kind: ErrChannelMax, Code: reply.Code, Err: err, retryable: true}
+
+	// The driver reports a missing or forbidden vhost as an access refusal.
+	case reply == amqp.ErrVhost,
+		reply.Code == amqp.AccessRefused && strings.Contains(strings.ToLower(reply.Reason), "vhost"):
+		return &Error{Kind: ErrVhostNotFound, Code: reply.Code, Err: err}
+
+	case reply.Code == amqp.AccessRefused:
+		return &Error{Kind: ErrAccessRefused, Code: reply.Code, Err: err}
+
+	default:
+		return &Error{Kind: ErrBroker, Code: reply.Code, Err: err, retryable: true}
+	}
+}
@@ -0,0 +1,75 @@
+package amqpx_test
+
+import (
+	"crypto/x509"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func TestIsRetryable(t *testing.T) {
+	is := NewRunner(t)
+
+	is.False(amqpx.IsRetryable(nil))
+	is.True(amqpx.IsRetryable(errors.New("unknown")))
+	is.True(amqpx.IsRetryable(errors.Wrap(x509.UnknownAuthorityError{}, amqpx.ErrMessageCannotOpenConnection)))
+	is.False(amqpx.IsRetryable(errors.Wrap(amqpx.ErrClientClosed, amqpx.ErrMessageCannotOpenChannel)))
+	is.True(amqpx.IsRetryable(errors.Wrap(amqpx.ErrNoConnectionAvailable, amqpx.ErrMessageCannotOpenChannel)))
+
+	is.False(amqpx.IsRetryable(errors.Wrap(amqp.ErrCredentials, amqpx.ErrMessageCannotOpenConnection)))
+	is.False(amqpx.IsRetryable(errors.Wrap(amqp.ErrVhost, amqpx.ErrMessageCannotOpenConnection)))
+	is.True(amqpx.IsRetryable(&amqp.Error{Code: amqp.NotAllowed, Reason: "NOT_ALLOWED - attempt to reuse consumer tag"}))
+	is.True(amqpx.IsRetryable(amqp.ErrClosed))
+	is.True(amqpx.IsRetryable(amqp.ErrChannelMax))
+	is.True(amqpx.IsRetryable(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED", Server: true}))
+	is.True(amqpx.IsRetryable(errors.Wrap(io.EOF, amqpx.ErrMessageCannotOpenConnection)))
+}
+
+func TestError_ConnectionRefused(t *testing.T) {
+	is := NewRunner(t)
+
+	dialer, err := amqpx.SimpleDialer(invalidBrokerURI)
+	is.NoError(err)
+
+	for _, option := range []amqpx.ClientOption{amqpx.WithCapacity(1), amqpx.WithoutConnectionsPool()} {
+		client, err := amqpx.New(dialer, option)
+		is.Error(err)
+		is.Nil(client)
+		is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+		is.True(amqpx.IsRetryable(err))
+		is.Contains(err.Error(), amqpx.ErrMessageDialRefused)
+
+		classified := &amqpx.Error{}
+		is.True(errors.As(err, &classified))
+		is.Equal(amqpx.ErrConnectionRefused, classified.Kind)
+		is.Equal(0, classified.Code)
+		is.True(classified.Retryable())
+	}
+}
+
+func TestError_VhostNotFound(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(strings.TrimSuffix(server.URI(), "/") + "/amqpx")
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithoutConnectionsPool())
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrVhostNotFound))
+	is.False(errors.Is(err, amqpx.ErrAccessRefused))
+	is.False(amqpx.IsRetryable(err))
+
+	classified := &amqpx.Error{}
+	is.True(errors.As(err, &classified))
+	is.Equal(amqpx.ErrVhostNotFound, classified.Kind)
+	is.Equal(amqp.AccessRefused, classified.Code)
+	is.Equal(amqp.ErrVhost, errors.Cause(classified.Err))
+}
@@ -7,7 +7,7 @@ import (
 	"os/signal"
 	"time"
 
-	"github.com/ulule/amqpx"
+	"github.com/ulule/amqpx/v3"
 )
 
 func main() {
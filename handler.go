@@ -0,0 +1,56 @@
+package amqpx
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Handler handles a delivery.
+type Handler func(ctx context.Context, delivery amqp.Delivery) error
+
+// Middleware wraps a Handler to add a cross-cutting behavior, such as logging or panic recovery.
+type Middleware func(Handler) Handler
+
+// Chain returns a Handler wrapping given handler with middlewares.
+// The first middleware is the outermost one, i.e. it's the first to handle a delivery.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// onceAcknowledger is an amqp.Acknowledger which only forwards the first acknowledgement of a delivery.
+// It allows a middleware or a handler to settle a delivery without having a double ack from its consumer.
+type onceAcknowledger struct {
+	once         sync.Once
+	acknowledger amqp.Acknowledger
+}
+
+func (e *onceAcknowledger) Ack(tag uint64, multiple bool) error {
+	var err error
+	e.once.Do(func() {
+		err = e.acknowledger.Ack(tag, multiple)
+	})
+	return err
+}
+
+func (e *onceAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	var err error
+	e.once.Do(func() {
+		err = e.acknowledger.Nack(tag, multiple, requeue)
+	})
+	return err
+}
+
+func (e *onceAcknowledger) Reject(tag uint64, requeue bool) error {
+	var err error
+	e.once.Do(func() {
+		err = e.acknowledger.Reject(tag, requeue)
+	})
+	return err
+}
+
+var _ amqp.Acknowledger = (*onceAcknowledger)(nil)
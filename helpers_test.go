@@ -6,18 +6,35 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
+	"testing"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
 
-	"github.com/ulule/amqpx"
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
 )
 
 const (
 	warpExchange = "amq.topic"
 )
 
+func NewServer(t *testing.T, broker *amqpxtest.Broker) *amqpxtest.Server {
+	t.Helper()
+
+	server, err := amqpxtest.NewServer(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		thr := server.Kill()
+		_ = thr
+	})
+
+	return server
+}
+
 func NewMessage(body []byte) amqp.Publishing {
 	return amqp.Publishing{
 		DeliveryMode:    amqp.Persistent,
@@ -28,6 +45,71 @@ func NewMessage(body []byte) amqp.Publishing {
 	}
 }
 
+type TestAcknowledger struct {
+	mutex   sync.Mutex
+	acks    int
+	nacks   int
+	requeue bool
+}
+
+func (e *TestAcknowledger) Ack(tag uint64, multiple bool) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.acks++
+	return nil
+}
+
+func (e *TestAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.nacks++
+	e.requeue = requeue
+	return nil
+}
+
+func (e *TestAcknowledger) Reject(tag uint64, requeue bool) error {
+	return e.Nack(tag, false, requeue)
+}
+
+type TestLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (e *TestLogger) log(level string, args ...interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.messages = append(e.messages, level+": "+fmt.Sprint(args...))
+}
+
+func (e *TestLogger) Messages() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string{}, e.messages...)
+}
+
+func (e *TestLogger) Debug(args ...interface{}) { e.log("DEBUG", args...) }
+func (e *TestLogger) Info(args ...interface{})  { e.log("INFO", args...) }
+func (e *TestLogger) Warn(args ...interface{})  { e.log("WARN", args...) }
+func (e *TestLogger) Error(args ...interface{}) { e.log("ERROR", args...) }
+
+type SafeBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (e *SafeBuffer) Write(p []byte) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.buffer.Write(p)
+}
+
+func (e *SafeBuffer) String() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.buffer.String()
+}
+
 type Event struct {
 	Message string
 }
@@ -40,7 +122,7 @@ func NewEmitter(client amqpx.Client) *Emitter {
 	return &Emitter{client: client}
 }
 
-func (emitter *Emitter) setDirectChannel(channel *amqp.Channel, topic string) error {
+func (emitter *Emitter) setDirectChannel(channel amqpx.Channel, topic string) error {
 	directQueue, err := channel.QueueDeclare(
 		topic,
 		true,  // durable
@@ -286,3 +368,16 @@ func GenerateMessages() []string {
 
 	return buffer
 }
+
+func DeclareQueue(client amqpx.Client, topic string) error {
+	channel, err := client.Channel()
+	if err != nil {
+		return errors.Wrap(err, "cannot acquire a channel")
+	}
+	defer func() {
+		thr := channel.Close()
+		_ = thr
+	}()
+
+	return NewEmitter(client).setDirectChannel(channel, topic)
+}
@@ -0,0 +1,120 @@
+package amqpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// PublishFunc publishes a message on given exchange with given routing key.
+type PublishFunc func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error
+
+// PublishInterceptor wraps a PublishFunc to add a cross-cutting behavior, such as stamping or validating a message.
+type PublishInterceptor func(PublishFunc) PublishFunc
+
+// ChainPublish returns a PublishFunc wrapping given function with interceptors.
+// The first interceptor is the outermost one, i.e. it's the first to receive a message.
+func ChainPublish(publish PublishFunc, interceptors ...PublishInterceptor) PublishFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		publish = interceptors[i](publish)
+	}
+	return publish
+}
+
+// PublishResult describes the outcome of a publishing.
+type PublishResult struct {
+	Exchange   string
+	RoutingKey string
+	MessageID  string
+	// Err is nil if the message was published, and confirmed if the publisher uses confirm mode.
+	// If the broker has rejected the message, it wraps ErrPublishNacked.
+	Err error
+	// Latency is the time spent to publish the message, including the confirmation delay.
+	Latency time.Duration
+}
+
+// ResultInterceptor returns a PublishInterceptor that gives the outcome of every publishing to given callback.
+func ResultInterceptor(callback func(PublishResult)) PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+			start := time.Now()
+			err := next(ctx, exchange, key, message)
+
+			callback(PublishResult{
+				Exchange:   exchange,
+				RoutingKey: key,
+				MessageID:  message.MessageId,
+				Err:        err,
+				Latency:    time.Since(start),
+			})
+
+			return err
+		}
+	}
+}
+
+// MessageIDInterceptor returns a PublishInterceptor that stamps a message id on messages without one.
+// If generator is nil, a random 128-bit hexadecimal id is used.
+func MessageIDInterceptor(generator func() string) PublishInterceptor {
+	if generator == nil {
+		generator = randomMessageID
+	}
+
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+			if message.MessageId == "" {
+				message.MessageId = generator()
+			}
+			return next(ctx, exchange, key, message)
+		}
+	}
+}
+
+// TimestampInterceptor returns a PublishInterceptor that stamps current time on messages without a timestamp.
+func TimestampInterceptor() PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+			if message.Timestamp.IsZero() {
+				message.Timestamp = time.Now()
+			}
+			return next(ctx, exchange, key, message)
+		}
+	}
+}
+
+// AppIDInterceptor returns a PublishInterceptor that stamps given application id on messages without one.
+func AppIDInterceptor(appID string) PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+			if message.AppId == "" {
+				message.AppId = appID
+			}
+			return next(ctx, exchange, key, message)
+		}
+	}
+}
+
+// MaxBodySizeInterceptor returns a PublishInterceptor that refuses messages with a body larger than given size.
+func MaxBodySizeInterceptor(size int) PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+			if len(message.Body) > size {
+				return errors.Wrapf(ErrMessageTooLarge, "%d bytes exceeds %d bytes", len(message.Body), size)
+			}
+			return next(ctx, exchange, key, message)
+		}
+	}
+}
+
+func randomMessageID() string {
+	buffer := make([]byte, 16)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buffer)
+}
@@ -0,0 +1,100 @@
+package amqpx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestChainPublish(t *testing.T) {
+	is := NewRunner(t)
+
+	var sent amqp.Publishing
+	publish := amqpx.ChainPublish(func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+		sent = *message
+		return nil
+	},
+		amqpx.MessageIDInterceptor(func() string { return "42" }),
+		amqpx.TimestampInterceptor(),
+		amqpx.AppIDInterceptor("amqpx"),
+	)
+
+	is.NoError(publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{}))
+	is.Equal("42", sent.MessageId)
+	is.Equal("amqpx", sent.AppId)
+	is.False(sent.Timestamp.IsZero())
+
+	timestamp := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	is.NoError(publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{
+		MessageId: "1",
+		AppId:     "other",
+		Timestamp: timestamp,
+	}))
+	is.Equal("1", sent.MessageId)
+	is.Equal("other", sent.AppId)
+	is.Equal(timestamp, sent.Timestamp)
+}
+
+func TestMessageIDInterceptor_Random(t *testing.T) {
+	is := NewRunner(t)
+
+	ids := []string{}
+	publish := amqpx.ChainPublish(func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+		ids = append(ids, message.MessageId)
+		return nil
+	}, amqpx.MessageIDInterceptor(nil))
+
+	is.NoError(publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{}))
+	is.NoError(publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{}))
+	is.Equal(32, len(ids[0]))
+	is.True(ids[0] != ids[1])
+}
+
+func TestMaxBodySizeInterceptor(t *testing.T) {
+	is := NewRunner(t)
+
+	calls := 0
+	publish := amqpx.ChainPublish(func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+		calls++
+		return nil
+	}, amqpx.MaxBodySizeInterceptor(4))
+
+	is.NoError(publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{Body: []byte("1234")}))
+
+	err := publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{Body: []byte("12345")})
+	is.Error(err)
+	is.Equal(amqpx.ErrMessageTooLarge, errors.Cause(err))
+	is.Equal(1, calls)
+}
+
+func TestResultInterceptor(t *testing.T) {
+	is := NewRunner(t)
+
+	results := []amqpx.PublishResult{}
+	failure := errors.New("failure")
+	publish := amqpx.ChainPublish(func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+		time.Sleep(5 * time.Millisecond)
+		if message.MessageId == "2" {
+			return failure
+		}
+		return nil
+	}, amqpx.ResultInterceptor(func(result amqpx.PublishResult) {
+		results = append(results, result)
+	}))
+
+	is.NoError(publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{MessageId: "1"}))
+	is.Equal(failure, publish(context.Background(), warpExchange, "random.interceptor", &amqp.Publishing{MessageId: "2"}))
+
+	is.Equal(2, len(results))
+	is.Equal(warpExchange, results[0].Exchange)
+	is.Equal("random.interceptor", results[0].RoutingKey)
+	is.Equal("1", results[0].MessageID)
+	is.Nil(results[0].Err)
+	is.True(results[0].Latency >= 5*time.Millisecond)
+	is.Equal(failure, results[1].Err)
+}
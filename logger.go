@@ -49,3 +49,11 @@ type Logger interface {
 	Warn(args ...interface{})
 	Error(args ...interface{})
 }
+
+// StructuredLogger describes a leveled logger with key/value fields.
+type StructuredLogger interface {
+	Debug(message string, fields ...Field)
+	Info(message string, fields ...Field)
+	Warn(message string, fields ...Field)
+	Error(message string, fields ...Field)
+}
@@ -0,0 +1,146 @@
+package amqpx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FieldSuppressed is the field key of the number of suppressed messages.
+const FieldSuppressed = "suppressed"
+
+// dedupLogger is a StructuredLogger which suppresses repeated messages within a window.
+type dedupLogger struct {
+	mutex   sync.Mutex
+	logger  StructuredLogger
+	window  time.Duration
+	entries map[dedupKey]int
+}
+
+// dedupKey identifies similar messages: the error, URI and pool fields are compared, other fields, such as a slot,
+// are ignored.
+type dedupKey struct {
+	level   LoggerLevel
+	message string
+	err     interface{}
+	uri     interface{}
+	pool    interface{}
+}
+
+// newDedupKey returns the key of given message.
+func newDedupKey(level LoggerLevel, message string, fields []Field) dedupKey {
+	key := dedupKey{level: level, message: message}
+	for _, field := range fields {
+		switch field.Key {
+		case FieldError:
+			key.err = field.Value
+		case FieldURI:
+			key.uri = field.Value
+		case FieldPool:
+			key.pool = field.Value
+		}
+	}
+	return key
+}
+
+// fields returns the compared fields of the key.
+func (k dedupKey) fields() []Field {
+	fields := []Field{}
+	if k.err != nil {
+		fields = append(fields, Field{Key: FieldError, Value: k.err})
+	}
+	if k.uri != nil {
+		fields = append(fields, Field{Key: FieldURI, Value: k.uri})
+	}
+	if k.pool != nil {
+		fields = append(fields, Field{Key: FieldPool, Value: k.pool})
+	}
+	return fields
+}
+
+// NewDedupLogger returns a StructuredLogger which forwards the first occurrence of a warning or an error to given
+// logger, and suppresses similar messages (same level, message, error, URI and pool, whatever their other fields)
+// within given window. At the end of the window, a summary with the number of suppressed messages is emitted.
+// Debug and info messages are always forwarded.
+func NewDedupLogger(logger StructuredLogger, window time.Duration) StructuredLogger {
+	return &dedupLogger{
+		logger:  logger,
+		window:  window,
+		entries: map[dedupKey]int{},
+	}
+}
+
+// Debug implements StructuredLogger interface.
+func (l *dedupLogger) Debug(message string, fields ...Field) {
+	l.log(LoggerLevelDebug, message, fields)
+}
+
+// Info implements StructuredLogger interface.
+func (l *dedupLogger) Info(message string, fields ...Field) {
+	l.log(LoggerLevelInfo, message, fields)
+}
+
+// Warn implements StructuredLogger interface.
+func (l *dedupLogger) Warn(message string, fields ...Field) {
+	l.log(LoggerLevelWarn, message, fields)
+}
+
+// Error implements StructuredLogger interface.
+func (l *dedupLogger) Error(message string, fields ...Field) {
+	l.log(LoggerLevelError, message, fields)
+}
+
+func (l *dedupLogger) log(level LoggerLevel, message string, fields []Field) {
+	if level != LoggerLevelWarn && level != LoggerLevelError {
+		l.forward(level, message, fields)
+		return
+	}
+
+	key := newDedupKey(level, message, fields)
+
+	l.mutex.Lock()
+	count, ok := l.entries[key]
+	if ok {
+		l.entries[key] = count + 1
+		l.mutex.Unlock()
+		return
+	}
+	l.entries[key] = 0
+	l.mutex.Unlock()
+
+	time.AfterFunc(l.window, func() {
+		l.flush(key)
+	})
+
+	l.forward(level, message, fields)
+}
+
+// flush ends the window of given key, and emits a summary if similar messages were suppressed.
+func (l *dedupLogger) flush(key dedupKey) {
+	l.mutex.Lock()
+	count := l.entries[key]
+	delete(l.entries, key)
+	l.mutex.Unlock()
+
+	if count == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("%s (suppressed %d similar messages)", key.message, count)
+	l.forward(key.level, message, append(key.fields(), Field{Key: FieldSuppressed, Value: count}))
+}
+
+func (l *dedupLogger) forward(level LoggerLevel, message string, fields []Field) {
+	switch level {
+	case LoggerLevelDebug:
+		l.logger.Debug(message, fields...)
+	case LoggerLevelInfo:
+		l.logger.Info(message, fields...)
+	case LoggerLevelWarn:
+		l.logger.Warn(message, fields...)
+	case LoggerLevelError:
+		l.logger.Error(message, fields...)
+	}
+}
+
+var _ StructuredLogger = (*dedupLogger)(nil)
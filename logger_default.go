@@ -1,74 +1,173 @@
 package amqpx
 
 import (
-	"log"
-	"os"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	defaultLoggerPrefix = "[amqpx] "
 )
 
-// defaultLogger is the default Logger implementation using log.Logger.
+// defaultLogger is the default StructuredLogger implementation, which writes a message per line.
 type defaultLogger struct {
-	level LoggerLevel
-	debug *log.Logger
-	info  *log.Logger
-	warn  *log.Logger
-	err   *log.Logger
+	mutex  sync.Mutex
+	level  LoggerLevel
+	format LoggerFormat
+	layout string
+	prefix string
+	out    io.Writer
+	err    io.Writer
 }
 
-// newDefaultLogger returns a new defaultLogger instance.
-func newDefaultLogger(level LoggerLevel) Logger {
-	if level == LoggerLevelDisabled {
-		return &noopLogger{}
+// NewDefaultLogger returns a new StructuredLogger which writes messages up to given level.
+// By default, it writes text messages with a timestamp in seconds on os.Stdout, and errors on os.Stderr.
+func NewDefaultLogger(level LoggerLevel, options ...LoggerOption) (StructuredLogger, error) {
+	opts := newLoggerOptions()
+	for _, option := range options {
+		err := option.apply(&opts)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrMessageCannotCreateLogger)
+		}
 	}
 
-	flags := log.Ldate | log.Ltime
+	if level == LoggerLevelDisabled {
+		return noopLogger{}, nil
+	}
 
 	return &defaultLogger{
-		level: level,
-		debug: log.New(os.Stdout, defaultLoggerPrefix+"DEBUG: ", flags),
-		info:  log.New(os.Stdout, defaultLoggerPrefix+"INFO: ", flags),
-		warn:  log.New(os.Stdout, defaultLoggerPrefix+"WARN: ", flags),
-		err:   log.New(os.Stderr, defaultLoggerPrefix+"ERROR: ", flags),
-	}
+		level:  level,
+		format: opts.format,
+		layout: timestampLayout(opts.format, opts.precision),
+		prefix: opts.prefix,
+		out:    opts.out,
+		err:    opts.err,
+	}, nil
 }
 
-// Debug implements Logger interface.
-func (l defaultLogger) Debug(args ...interface{}) {
-	if l.level < LoggerLevelDebug {
-		return
+// newDefaultLogger returns a new defaultLogger instance with its default configuration.
+func newDefaultLogger(level LoggerLevel) StructuredLogger {
+	logger, err := NewDefaultLogger(level)
+	if err != nil {
+		return noopLogger{}
 	}
+	return logger
+}
 
-	l.debug.Println(args...)
+// Debug implements StructuredLogger interface.
+func (l *defaultLogger) Debug(message string, fields ...Field) {
+	l.log(LoggerLevelDebug, message, fields)
 }
 
-// Info implements Logger interface.
-func (l defaultLogger) Info(args ...interface{}) {
-	if l.level < LoggerLevelInfo {
+// Info implements StructuredLogger interface.
+func (l *defaultLogger) Info(message string, fields ...Field) {
+	l.log(LoggerLevelInfo, message, fields)
+}
+
+// Warn implements StructuredLogger interface.
+func (l *defaultLogger) Warn(message string, fields ...Field) {
+	l.log(LoggerLevelWarn, message, fields)
+}
+
+// Error implements StructuredLogger interface.
+func (l *defaultLogger) Error(message string, fields ...Field) {
+	l.log(LoggerLevelError, message, fields)
+}
+
+func (l *defaultLogger) log(level LoggerLevel, message string, fields []Field) {
+	if l.level < level {
 		return
 	}
 
-	l.info.Println(args...)
+	timestamp := time.Now().Format(l.layout)
+
+	var line []byte
+	if l.format == LoggerFormatJSON {
+		line = formatJSON(timestamp, level, message, fields)
+	} else {
+		line = l.formatText(timestamp, level, message, fields)
+	}
+
+	writer := l.out
+	if level == LoggerLevelError {
+		writer = l.err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, _ = writer.Write(line)
 }
 
-// Warn implements Logger interface.
-func (l defaultLogger) Warn(args ...interface{}) {
-	if l.level < LoggerLevelWarn {
-		return
+// formatText returns a text line, such as: "[amqpx] DEBUG: 2018/01/01 10:00:00 Opened connection slot=0".
+func (l *defaultLogger) formatText(timestamp string, level LoggerLevel, message string, fields []Field) []byte {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(l.prefix)
+	buffer.WriteString(loggerLevelLabels[level])
+	buffer.WriteString(": ")
+	buffer.WriteString(timestamp)
+	buffer.WriteString(" ")
+	buffer.WriteString(formatFields(message, fields))
+	buffer.WriteString("\n")
+	return buffer.Bytes()
+}
+
+// formatJSON returns a JSON object line, with its time, level, message and fields.
+func formatJSON(timestamp string, level LoggerLevel, message string, fields []Field) []byte {
+	entry := make(map[string]interface{}, len(fields)+3)
+	for _, field := range fields {
+		entry[field.Key] = jsonValue(field.Value)
 	}
+	entry["time"] = timestamp
+	entry["level"] = loggerLevelNames[level]
+	entry["message"] = message
 
-	l.warn.Println(args...)
+	buffer, err := json.Marshal(entry)
+	if err != nil {
+		buffer, _ = json.Marshal(map[string]interface{}{
+			"time":    timestamp,
+			"level":   loggerLevelNames[level],
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+
+	return append(buffer, '\n')
 }
 
-// Error implements Logger interface.
-func (l defaultLogger) Error(args ...interface{}) {
-	if l.level < LoggerLevelError {
-		return
+// timestampLayout returns the time layout of given format and precision.
+func timestampLayout(format LoggerFormat, precision LoggerPrecision) string {
+	layouts := map[LoggerPrecision]string{
+		LoggerPrecisionSeconds:      "2006/01/02 15:04:05",
+		LoggerPrecisionMilliseconds: "2006/01/02 15:04:05.000",
+		LoggerPrecisionMicroseconds: "2006/01/02 15:04:05.000000",
+	}
+	if format == LoggerFormatJSON {
+		layouts = map[LoggerPrecision]string{
+			LoggerPrecisionSeconds:      "2006-01-02T15:04:05Z07:00",
+			LoggerPrecisionMilliseconds: "2006-01-02T15:04:05.000Z07:00",
+			LoggerPrecisionMicroseconds: "2006-01-02T15:04:05.000000Z07:00",
+		}
 	}
+	return layouts[precision]
+}
+
+var loggerLevelLabels = map[LoggerLevel]string{
+	LoggerLevelDebug: "DEBUG",
+	LoggerLevelInfo:  "INFO",
+	LoggerLevelWarn:  "WARN",
+	LoggerLevelError: "ERROR",
+}
 
-	l.err.Println(args...)
+var loggerLevelNames = map[LoggerLevel]string{
+	LoggerLevelDebug: LoggerLevelDebugStr,
+	LoggerLevelInfo:  LoggerLevelInfoStr,
+	LoggerLevelWarn:  LoggerLevelWarnStr,
+	LoggerLevelError: LoggerLevelErrorStr,
 }
 
-var _ Logger = (*defaultLogger)(nil)
+var _ StructuredLogger = (*defaultLogger)(nil)
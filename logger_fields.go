@@ -0,0 +1,73 @@
+package amqpx
+
+import (
+	"net"
+	"net/url"
+)
+
+// Logger field keys.
+const (
+	FieldSlot    = "slot"
+	FieldAddress = "address"
+	FieldURI     = "uri"
+	FieldError   = "error"
+	FieldAttempt = "attempt"
+	FieldPool    = "pool"
+)
+
+// Field is a key/value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// SlotField returns a Field with the connection slot in a pool.
+func SlotField(slot int) Field {
+	return Field{Key: FieldSlot, Value: slot}
+}
+
+// AddressField returns a Field with the local address of a connection.
+func AddressField(address net.Addr) Field {
+	return Field{Key: FieldAddress, Value: addressString(address)}
+}
+
+// addressString returns given address as a string, or an empty string if it's nil.
+func addressString(address net.Addr) string {
+	if address == nil {
+		return ""
+	}
+	return address.String()
+}
+
+// URIField returns a Field with a broker URI, with its password redacted.
+func URIField(uri string) Field {
+	return Field{Key: FieldURI, Value: RedactURI(uri)}
+}
+
+// ErrorField returns a Field with an error.
+func ErrorField(err error) Field {
+	value := ""
+	if err != nil {
+		value = err.Error()
+	}
+	return Field{Key: FieldError, Value: value}
+}
+
+// AttemptField returns a Field with the number of a connection attempt.
+func AttemptField(attempt int) Field {
+	return Field{Key: FieldAttempt, Value: attempt}
+}
+
+// PoolField returns a Field with the name of a connections pool of a Split client.
+func PoolField(pool string) Field {
+	return Field{Key: FieldPool, Value: pool}
+}
+
+// RedactURI returns given broker URI with its password redacted.
+func RedactURI(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "<invalid uri>"
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,34 @@
+package amqpx
+
+import (
+	"fmt"
+	"io"
+)
+
+// NewJSONLogger returns a StructuredLogger which writes messages up to given level on given writer,
+// as a JSON object per line.
+func NewJSONLogger(writer io.Writer, level LoggerLevel) StructuredLogger {
+	if level == LoggerLevelDisabled || writer == nil {
+		return noopLogger{}
+	}
+
+	return &defaultLogger{
+		level:  level,
+		format: LoggerFormatJSON,
+		layout: timestampLayout(LoggerFormatJSON, LoggerPrecisionMicroseconds),
+		out:    writer,
+		err:    writer,
+	}
+}
+
+// jsonValue returns a value that can be encoded as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case error:
+		return value.Error()
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return value
+	}
+}
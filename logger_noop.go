@@ -2,7 +2,7 @@ package amqpx
 
 type noopLogger struct{}
 
-func (l noopLogger) Debug(args ...interface{}) {}
-func (l noopLogger) Info(args ...interface{})  {}
-func (l noopLogger) Warn(args ...interface{})  {}
-func (l noopLogger) Error(args ...interface{}) {}
+func (l noopLogger) Debug(message string, fields ...Field) {}
+func (l noopLogger) Info(message string, fields ...Field)  {}
+func (l noopLogger) Warn(message string, fields ...Field)  {}
+func (l noopLogger) Error(message string, fields ...Field) {}
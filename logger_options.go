@@ -0,0 +1,97 @@
+package amqpx
+
+import (
+	"io"
+	"os"
+)
+
+// LoggerFormat represents the output format of the default logger.
+type LoggerFormat uint8
+
+// Logger formats
+const (
+	LoggerFormatText LoggerFormat = iota
+	LoggerFormatJSON
+)
+
+// LoggerPrecision represents the timestamp precision of the default logger.
+type LoggerPrecision uint8
+
+// Logger timestamp precisions
+const (
+	LoggerPrecisionSeconds LoggerPrecision = iota
+	LoggerPrecisionMilliseconds
+	LoggerPrecisionMicroseconds
+)
+
+// LoggerOption is used to define the default logger configuration.
+type LoggerOption interface {
+	apply(*loggerOptions) error
+}
+
+type loggerOption func(*loggerOptions) error
+
+func (o loggerOption) apply(instance *loggerOptions) error {
+	return o(instance)
+}
+
+type loggerOptions struct {
+	out       io.Writer
+	err       io.Writer
+	format    LoggerFormat
+	precision LoggerPrecision
+	prefix    string
+}
+
+func newLoggerOptions() loggerOptions {
+	return loggerOptions{
+		out:       os.Stdout,
+		err:       os.Stderr,
+		format:    LoggerFormatText,
+		precision: LoggerPrecisionSeconds,
+		prefix:    defaultLoggerPrefix,
+	}
+}
+
+// WithLoggerOutput will configure the default logger to write every message on the given writer.
+func WithLoggerOutput(writer io.Writer) LoggerOption {
+	return loggerOption(func(options *loggerOptions) error {
+		if writer == nil {
+			return ErrLoggerOutputRequired
+		}
+		options.out = writer
+		options.err = writer
+		return nil
+	})
+}
+
+// WithLoggerFormat will configure the default logger with the given format.
+func WithLoggerFormat(format LoggerFormat) LoggerOption {
+	return loggerOption(func(options *loggerOptions) error {
+		if format != LoggerFormatText && format != LoggerFormatJSON {
+			return ErrInvalidLoggerFormat
+		}
+		options.format = format
+		return nil
+	})
+}
+
+// WithLoggerPrecision will configure the default logger with the given timestamp precision.
+func WithLoggerPrecision(precision LoggerPrecision) LoggerOption {
+	return loggerOption(func(options *loggerOptions) error {
+		if precision > LoggerPrecisionMicroseconds {
+			return ErrInvalidLoggerPrecision
+		}
+		options.precision = precision
+		return nil
+	})
+}
+
+// WithLoggerPrefix will configure the default logger with the given prefix.
+// The prefix is only used by the text format.
+func WithLoggerPrefix(prefix string) LoggerOption {
+	return loggerOption(func(options *loggerOptions) error {
+		options.prefix = prefix
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package amqpx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loggerShim is a StructuredLogger that forwards its messages to a Logger, with its fields formatted as key=value.
+type loggerShim struct {
+	logger Logger
+}
+
+// NewLoggerShim returns a StructuredLogger forwarding its messages to given Logger,
+// with its fields formatted as key=value.
+func NewLoggerShim(logger Logger) StructuredLogger {
+	return &loggerShim{logger: logger}
+}
+
+// Debug implements StructuredLogger interface.
+func (l loggerShim) Debug(message string, fields ...Field) {
+	l.logger.Debug(formatFields(message, fields))
+}
+
+// Info implements StructuredLogger interface.
+func (l loggerShim) Info(message string, fields ...Field) {
+	l.logger.Info(formatFields(message, fields))
+}
+
+// Warn implements StructuredLogger interface.
+func (l loggerShim) Warn(message string, fields ...Field) {
+	l.logger.Warn(formatFields(message, fields))
+}
+
+// Error implements StructuredLogger interface.
+func (l loggerShim) Error(message string, fields ...Field) {
+	l.logger.Error(formatFields(message, fields))
+}
+
+// formatFields returns given message followed by its fields formatted as key=value.
+func formatFields(message string, fields []Field) string {
+	if len(fields) == 0 {
+		return message
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString(message)
+	for _, field := range fields {
+		value := fmt.Sprint(field.Value)
+		if strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		builder.WriteString(" ")
+		builder.WriteString(field.Key)
+		builder.WriteString("=")
+		builder.WriteString(value)
+	}
+
+	return builder.String()
+}
+
+var _ StructuredLogger = (*loggerShim)(nil)
@@ -0,0 +1,52 @@
+package amqpx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger is a StructuredLogger using log/slog.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a StructuredLogger using given slog.Logger.
+func NewSlogLogger(logger *slog.Logger) StructuredLogger {
+	return &slogLogger{logger: logger}
+}
+
+// Debug implements StructuredLogger interface.
+func (l slogLogger) Debug(message string, fields ...Field) {
+	l.log(slog.LevelDebug, message, fields)
+}
+
+// Info implements StructuredLogger interface.
+func (l slogLogger) Info(message string, fields ...Field) {
+	l.log(slog.LevelInfo, message, fields)
+}
+
+// Warn implements StructuredLogger interface.
+func (l slogLogger) Warn(message string, fields ...Field) {
+	l.log(slog.LevelWarn, message, fields)
+}
+
+// Error implements StructuredLogger interface.
+func (l slogLogger) Error(message string, fields ...Field) {
+	l.log(slog.LevelError, message, fields)
+}
+
+func (l slogLogger) log(level slog.Level, message string, fields []Field) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, field.Value))
+	}
+
+	l.logger.LogAttrs(ctx, level, message, attrs...)
+}
+
+var _ StructuredLogger = (*slogLogger)(nil)
@@ -0,0 +1,164 @@
+package amqpx
+
+import (
+	"time"
+)
+
+// Metrics is a numeric metrics collector.
+type Metrics interface {
+	// OnDial is called after each dial attempt, with its duration.
+	OnDial(duration time.Duration, err error)
+
+	// OnConnectionOpen is called when a connection is opened.
+	OnConnectionOpen()
+
+	// OnConnectionClose is called when a connection is closed.
+	OnConnectionClose()
+
+	// OnReconnect is called when a lost connection is replaced by a new one.
+	OnReconnect()
+
+	// OnConnectionWait is called with the time spent waiting for a connection when a channel is requested.
+	OnConnectionWait(duration time.Duration)
+
+	// OnChannelOpen is called after each attempt to open a channel.
+	OnChannelOpen(err error)
+}
+
+// PoolMetrics is implemented by Metrics which record the events of each connections pool of a Split client
+// separately.
+type PoolMetrics interface {
+	// Pool returns the Metrics of the connections pool with given name.
+	Pool(name string) Metrics
+}
+
+// Metric names used by a Metrics instance created with NewMetrics.
+const (
+	MetricConnectionsOpen     = "connections_open"
+	MetricReconnects          = "reconnects_total"
+	MetricDialDuration        = "dial_duration_seconds"
+	MetricDialFailures        = "dial_failures_total"
+	MetricConnectionWait      = "connection_wait_seconds"
+	MetricChannelsOpened      = "channels_opened_total"
+	MetricChannelOpenFailures = "channel_open_failures_total"
+)
+
+// Counter is a metric that only goes up.
+type Counter interface {
+	Inc()
+}
+
+// Gauge is a metric that can go up and down.
+type Gauge interface {
+	Add(delta float64)
+}
+
+// Histogram is a metric that samples observations, such as durations in seconds.
+type Histogram interface {
+	Observe(value float64)
+}
+
+// MetricsRegistry creates metrics by name.
+// Prometheus or StatsD bindings only have to implement this interface to be used with NewMetrics.
+type MetricsRegistry interface {
+	Counter(name string) Counter
+	Gauge(name string) Gauge
+	Histogram(name string) Histogram
+}
+
+// NewMetrics returns a Metrics instance which records events using metrics created by given registry.
+// With a Split client, the metrics of each connections pool are prefixed by its name, such as
+// "publish_connections_open".
+func NewMetrics(registry MetricsRegistry) Metrics {
+	return &registryMetrics{
+		registry:            registry,
+		connectionsOpen:     registry.Gauge(MetricConnectionsOpen),
+		reconnects:          registry.Counter(MetricReconnects),
+		dialDuration:        registry.Histogram(MetricDialDuration),
+		dialFailures:        registry.Counter(MetricDialFailures),
+		connectionWait:      registry.Histogram(MetricConnectionWait),
+		channelsOpened:      registry.Counter(MetricChannelsOpened),
+		channelOpenFailures: registry.Counter(MetricChannelOpenFailures),
+	}
+}
+
+// registryMetrics is a Metrics implementation using a MetricsRegistry.
+type registryMetrics struct {
+	registry            MetricsRegistry
+	connectionsOpen     Gauge
+	reconnects          Counter
+	dialDuration        Histogram
+	dialFailures        Counter
+	connectionWait      Histogram
+	channelsOpened      Counter
+	channelOpenFailures Counter
+}
+
+// OnDial implements Metrics interface.
+func (e *registryMetrics) OnDial(duration time.Duration, err error) {
+	e.dialDuration.Observe(duration.Seconds())
+	if err != nil {
+		e.dialFailures.Inc()
+	}
+}
+
+// OnConnectionOpen implements Metrics interface.
+func (e *registryMetrics) OnConnectionOpen() {
+	e.connectionsOpen.Add(1)
+}
+
+// OnConnectionClose implements Metrics interface.
+func (e *registryMetrics) OnConnectionClose() {
+	e.connectionsOpen.Add(-1)
+}
+
+// OnReconnect implements Metrics interface.
+func (e *registryMetrics) OnReconnect() {
+	e.reconnects.Inc()
+}
+
+// OnConnectionWait implements Metrics interface.
+func (e *registryMetrics) OnConnectionWait(duration time.Duration) {
+	e.connectionWait.Observe(duration.Seconds())
+}
+
+// OnChannelOpen implements Metrics interface.
+func (e *registryMetrics) OnChannelOpen(err error) {
+	if err != nil {
+		e.channelOpenFailures.Inc()
+		return
+	}
+	e.channelsOpened.Inc()
+}
+
+// Pool implements PoolMetrics interface.
+func (e *registryMetrics) Pool(name string) Metrics {
+	return NewMetrics(&prefixRegistry{registry: e.registry, prefix: name + "_"})
+}
+
+// prefixRegistry is a MetricsRegistry which prefixes the name of its metrics.
+type prefixRegistry struct {
+	registry MetricsRegistry
+	prefix   string
+}
+
+// Counter implements MetricsRegistry interface.
+func (e *prefixRegistry) Counter(name string) Counter {
+	return e.registry.Counter(e.prefix + name)
+}
+
+// Gauge implements MetricsRegistry interface.
+func (e *prefixRegistry) Gauge(name string) Gauge {
+	return e.registry.Gauge(e.prefix + name)
+}
+
+// Histogram implements MetricsRegistry interface.
+func (e *prefixRegistry) Histogram(name string) Histogram {
+	return e.registry.Histogram(e.prefix + name)
+}
+
+var (
+	_ Metrics         = (*registryMetrics)(nil)
+	_ PoolMetrics     = (*registryMetrics)(nil)
+	_ MetricsRegistry = (*prefixRegistry)(nil)
+)
@@ -0,0 +1,163 @@
+package amqpx
+
+import (
+	"encoding/json"
+	"expvar"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultHistogramBuckets are the upper bounds, in seconds, of histograms created by an ExpvarRegistry.
+var DefaultHistogramBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30}
+
+// expvarMutex prevents a concurrent registration of the same expvar variable.
+var expvarMutex sync.Mutex
+
+// NewExpvarMetrics returns a Metrics instance published with expvar under given name.
+func NewExpvarMetrics(name string) (Metrics, error) {
+	registry, err := NewExpvarRegistry(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewMetrics(registry), nil
+}
+
+// ExpvarRegistry is a MetricsRegistry that publishes its metrics with expvar, using an expvar.Map.
+type ExpvarRegistry struct {
+	vars *expvar.Map
+}
+
+// NewExpvarRegistry returns a new ExpvarRegistry which publishes its metrics under given name.
+// If an expvar.Map is already published under this name, it will be reused. If another variable is published
+// under this name, an error is returned.
+func NewExpvarRegistry(name string) (*ExpvarRegistry, error) {
+	expvarMutex.Lock()
+	defer expvarMutex.Unlock()
+
+	published := expvar.Get(name)
+	if published == nil {
+		return &ExpvarRegistry{vars: expvar.NewMap(name)}, nil
+	}
+
+	vars, ok := published.(*expvar.Map)
+	if !ok {
+		return nil, errors.Wrapf(ErrExpvarNameConflict, "%s", name)
+	}
+
+	return &ExpvarRegistry{vars: vars}, nil
+}
+
+// Counter implements MetricsRegistry interface.
+func (e *ExpvarRegistry) Counter(name string) Counter {
+	return &expvarCounter{vars: e.vars, name: name}
+}
+
+// Gauge implements MetricsRegistry interface.
+func (e *ExpvarRegistry) Gauge(name string) Gauge {
+	return &expvarGauge{vars: e.vars, name: name}
+}
+
+// Histogram implements MetricsRegistry interface.
+func (e *ExpvarRegistry) Histogram(name string) Histogram {
+	expvarMutex.Lock()
+	defer expvarMutex.Unlock()
+
+	histogram, ok := e.vars.Get(name).(*expvarHistogram)
+	if !ok {
+		histogram = newExpvarHistogram(DefaultHistogramBuckets)
+		e.vars.Set(name, histogram)
+	}
+
+	return histogram
+}
+
+type expvarCounter struct {
+	vars *expvar.Map
+	name string
+}
+
+func (e *expvarCounter) Inc() {
+	e.vars.Add(e.name, 1)
+}
+
+type expvarGauge struct {
+	vars *expvar.Map
+	name string
+}
+
+func (e *expvarGauge) Add(delta float64) {
+	e.vars.AddFloat(e.name, delta)
+}
+
+// expvarHistogram is a cumulative histogram, rendered as JSON by expvar.
+type expvarHistogram struct {
+	mutex  sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newExpvarHistogram(bounds []float64) *expvarHistogram {
+	return &expvarHistogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (e *expvarHistogram) Observe(value float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for i := range e.bounds {
+		if value <= e.bounds[i] {
+			e.counts[i]++
+		}
+	}
+	e.count++
+	e.sum += value
+}
+
+// String implements expvar.Var interface.
+func (e *expvarHistogram) String() string {
+	type bucket struct {
+		UpperBound string `json:"le"`
+		Count      uint64 `json:"count"`
+	}
+
+	e.mutex.Lock()
+	buckets := make([]bucket, 0, len(e.bounds)+1)
+	for i := range e.bounds {
+		buckets = append(buckets, bucket{
+			UpperBound: strconv.FormatFloat(e.bounds[i], 'g', -1, 64),
+			Count:      e.counts[i],
+		})
+	}
+	buckets = append(buckets, bucket{UpperBound: "+Inf", Count: e.count})
+	value := struct {
+		Count   uint64   `json:"count"`
+		Sum     float64  `json:"sum"`
+		Buckets []bucket `json:"buckets"`
+	}{
+		Count:   e.count,
+		Sum:     e.sum,
+		Buckets: buckets,
+	}
+	e.mutex.Unlock()
+
+	if math.IsInf(value.Sum, 0) || math.IsNaN(value.Sum) {
+		value.Sum = 0
+	}
+
+	buffer, err := json.Marshal(value)
+	if err != nil {
+		return "{}"
+	}
+	return string(buffer)
+}
+
+var _ MetricsRegistry = (*ExpvarRegistry)(nil)
+var _ expvar.Var = (*expvarHistogram)(nil)
@@ -0,0 +1,14 @@
+package amqpx
+
+import (
+	"time"
+)
+
+type noopMetrics struct{}
+
+func (noopMetrics) OnDial(duration time.Duration, err error) {}
+func (noopMetrics) OnConnectionOpen()                        {}
+func (noopMetrics) OnConnectionClose()                       {}
+func (noopMetrics) OnReconnect()                             {}
+func (noopMetrics) OnConnectionWait(duration time.Duration)  {}
+func (noopMetrics) OnChannelOpen(err error)                  {}
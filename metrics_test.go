@@ -0,0 +1,87 @@
+package amqpx_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestMetrics(t *testing.T) {
+	is := NewRunner(t)
+
+	registry, err := amqpx.NewExpvarRegistry("amqpx_test_metrics")
+	is.NoError(err)
+	metrics := amqpx.NewMetrics(registry)
+
+	metrics.OnDial(20*time.Millisecond, nil)
+	metrics.OnDial(2*time.Second, errors.New("failure"))
+	metrics.OnConnectionOpen()
+	metrics.OnConnectionOpen()
+	metrics.OnConnectionClose()
+	metrics.OnReconnect()
+	metrics.OnConnectionWait(time.Millisecond)
+	metrics.OnChannelOpen(nil)
+	metrics.OnChannelOpen(errors.New("failure"))
+
+	vars := map[string]json.RawMessage{}
+	is.NoError(json.Unmarshal([]byte(expvar.Get("amqpx_test_metrics").String()), &vars))
+	is.Equal("1", string(vars[amqpx.MetricConnectionsOpen]))
+	is.Equal("1", string(vars[amqpx.MetricReconnects]))
+	is.Equal("1", string(vars[amqpx.MetricDialFailures]))
+	is.Equal("1", string(vars[amqpx.MetricChannelsOpened]))
+	is.Equal("1", string(vars[amqpx.MetricChannelOpenFailures]))
+
+	histogram := struct {
+		Count   int     `json:"count"`
+		Sum     float64 `json:"sum"`
+		Buckets []struct {
+			UpperBound string `json:"le"`
+			Count      int    `json:"count"`
+		} `json:"buckets"`
+	}{}
+	is.NoError(json.Unmarshal(vars[amqpx.MetricDialDuration], &histogram))
+	is.Equal(2, histogram.Count)
+	is.Equal(2.02, histogram.Sum)
+	is.Equal(len(amqpx.DefaultHistogramBuckets)+1, len(histogram.Buckets))
+	is.Equal("0.05", histogram.Buckets[3].UpperBound)
+	is.Equal(1, histogram.Buckets[3].Count)
+	is.Equal("+Inf", histogram.Buckets[len(histogram.Buckets)-1].UpperBound)
+	is.Equal(2, histogram.Buckets[len(histogram.Buckets)-1].Count)
+
+	// Creating a registry with the same name reuses its metrics.
+	metrics, err = amqpx.NewExpvarMetrics("amqpx_test_metrics")
+	is.NoError(err)
+	metrics.OnReconnect()
+	is.Equal("2", expvar.Get("amqpx_test_metrics").(*expvar.Map).Get(amqpx.MetricReconnects).String())
+
+	// A name published with another type cannot be used.
+	expvar.NewInt("amqpx_test_metrics_int")
+	_, err = amqpx.NewExpvarMetrics("amqpx_test_metrics_int")
+	is.Equal(amqpx.ErrExpvarNameConflict, errors.Cause(err))
+}
+
+func TestPoolClient_WithMetrics(t *testing.T) {
+	is := NewRunner(t)
+
+	metrics, err := amqpx.NewExpvarMetrics("amqpx_test_pool")
+	is.NoError(err)
+
+	client, err := NewClient(amqpx.WithCapacity(3), amqpx.WithMetrics(metrics))
+	is.NoError(err)
+	is.NotNil(client)
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+
+	vars := expvar.Get("amqpx_test_pool").(*expvar.Map)
+	is.Equal("3", vars.Get(amqpx.MetricConnectionsOpen).String())
+	is.Equal("1", vars.Get(amqpx.MetricChannelsOpened).String())
+
+	is.NoError(client.Close())
+}
@@ -0,0 +1,73 @@
+package amqpx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RecoverMiddleware returns a Middleware that recovers from a panic in the handler.
+// The delivery is then rejected, and requeued if requested, and an error wrapping ErrHandlerPanic is returned.
+func RecoverMiddleware(requeue bool) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, delivery amqp.Delivery) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+
+				err = errors.Wrap(ErrHandlerPanic, fmt.Sprint(r))
+				thr := delivery.Nack(false, requeue)
+				_ = thr
+			}()
+
+			return next(ctx, delivery)
+		}
+	}
+}
+
+// TimeoutMiddleware returns a Middleware that gives the handler a context canceled after given timeout.
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, delivery amqp.Delivery) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return next(ctx, delivery)
+		}
+	}
+}
+
+// LoggerMiddleware returns a Middleware that logs every handled delivery with given Logger.
+func LoggerMiddleware(logger Logger) Middleware {
+	return StructuredLoggerMiddleware(NewLoggerShim(logger))
+}
+
+// StructuredLoggerMiddleware returns a Middleware that logs every handled delivery with given StructuredLogger.
+func StructuredLoggerMiddleware(logger StructuredLogger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, delivery amqp.Delivery) error {
+			start := time.Now()
+			err := next(ctx, delivery)
+
+			fields := []Field{
+				{Key: "message_id", Value: delivery.MessageId},
+				{Key: "exchange", Value: delivery.Exchange},
+				{Key: "routing_key", Value: delivery.RoutingKey},
+				{Key: "duration", Value: time.Since(start).String()},
+			}
+
+			if err != nil {
+				logger.Error("Failed to handle message", append(fields, ErrorField(err))...)
+				return err
+			}
+
+			logger.Debug("Handled message", fields...)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package amqpx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestChain(t *testing.T) {
+	is := NewRunner(t)
+
+	calls := []string{}
+	middleware := func(name string) amqpx.Middleware {
+		return func(next amqpx.Handler) amqpx.Handler {
+			return func(ctx context.Context, delivery amqp.Delivery) error {
+				calls = append(calls, name)
+				return next(ctx, delivery)
+			}
+		}
+	}
+
+	handler := amqpx.Chain(func(ctx context.Context, delivery amqp.Delivery) error {
+		calls = append(calls, "handler")
+		return nil
+	}, middleware("first"), middleware("second"))
+
+	is.NoError(handler(context.Background(), amqp.Delivery{}))
+	is.Equal([]string{"first", "second", "handler"}, calls)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	is := NewRunner(t)
+
+	acknowledger := &TestAcknowledger{}
+	handler := amqpx.Chain(func(ctx context.Context, delivery amqp.Delivery) error {
+		panic("boom")
+	}, amqpx.RecoverMiddleware(true))
+
+	err := handler(context.Background(), amqp.Delivery{Acknowledger: acknowledger})
+	is.Error(err)
+	is.Equal(amqpx.ErrHandlerPanic, errors.Cause(err))
+	is.Contains(err.Error(), "boom")
+	is.Equal(1, acknowledger.nacks)
+	is.True(acknowledger.requeue)
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	is := NewRunner(t)
+
+	handler := amqpx.Chain(func(ctx context.Context, delivery amqp.Delivery) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, amqpx.TimeoutMiddleware(10*time.Millisecond))
+
+	err := handler(context.Background(), amqp.Delivery{})
+	is.Equal(context.DeadlineExceeded, err)
+}
+
+func TestStructuredLoggerMiddleware(t *testing.T) {
+	is := NewRunner(t)
+
+	buffer := &SafeBuffer{}
+	logger, err := amqpx.NewDefaultLogger(amqpx.LoggerLevelDebug, amqpx.WithLoggerOutput(buffer))
+	is.NoError(err)
+
+	failure := errors.New("failure")
+	handler := amqpx.Chain(func(ctx context.Context, delivery amqp.Delivery) error {
+		if delivery.MessageId == "2" {
+			return failure
+		}
+		return nil
+	}, amqpx.StructuredLoggerMiddleware(logger))
+
+	is.NoError(handler(context.Background(), amqp.Delivery{MessageId: "1", RoutingKey: "orders"}))
+	is.Equal(failure, handler(context.Background(), amqp.Delivery{MessageId: "2"}))
+
+	messages := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	is.Equal(2, len(messages))
+	is.Contains(messages[0], "DEBUG:")
+	is.Contains(messages[0], "Handled message message_id=1")
+	is.Contains(messages[0], "routing_key=orders")
+	is.Contains(messages[1], "ERROR:")
+	is.Contains(messages[1], "Failed to handle message message_id=2")
+	is.Contains(messages[1], "error=failure")
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	is := NewRunner(t)
+
+	logger := &TestLogger{}
+	handler := amqpx.Chain(func(ctx context.Context, delivery amqp.Delivery) error {
+		return nil
+	}, amqpx.LoggerMiddleware(logger))
+
+	is.NoError(handler(context.Background(), amqp.Delivery{MessageId: "1", RoutingKey: "orders"}))
+
+	messages := logger.Messages()
+	is.Equal(1, len(messages))
+	is.Contains(messages[0], "DEBUG: Handled message message_id=1")
+	is.Contains(messages[0], "routing_key=orders")
+}
@@ -1,20 +1,50 @@
 package amqpx
 
 import (
-	"fmt"
 	"io"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // Pooler default configuration.
 const (
 	// DefaultConnectionsCapacity is default connections pool capacity.
 	DefaultConnectionsCapacity = 10
+
+	// DefaultReconnectBackoffMin is the default minimum delay between two attempts to replace a lost connection.
+	DefaultReconnectBackoffMin = 200 * time.Millisecond
+
+	// DefaultReconnectBackoffMax is the default maximum delay between two attempts to replace a lost connection.
+	DefaultReconnectBackoffMax = time.Second
+)
+
+// backoff is the range of the random delay between two attempts to replace a lost connection.
+type backoff struct {
+	min time.Duration
+	max time.Duration
+}
+
+// delay returns a random delay within the range.
+func (e backoff) delay() time.Duration {
+	return e.min + time.Duration(rand.Int63n(int64(e.max-e.min)+1))
+}
+
+// SelectionStrategy defines how the connections pool picks a connection when a channel is requested.
+type SelectionStrategy uint8
+
+// Selection strategies
+const (
+	// SelectionRandom starts from a random connection, then tries the next ones. It's the default strategy.
+	SelectionRandom SelectionStrategy = iota
+	// SelectionRoundRobin starts from the connection following the previously selected one.
+	SelectionRoundRobin
+	// SelectionFirstAvailable always starts from the first connection, so the next ones are only used as fallbacks.
+	SelectionFirstAvailable
 )
 
 // Pool implements the Client interface using a connections pool.
@@ -23,8 +53,21 @@ type Pool struct {
 	mutex       sync.RWMutex
 	dialer      Dialer
 	observer    Observer
-	logger      Logger
-	connections []*amqp.Connection
+	logger      StructuredLogger
+	metrics     Metrics
+	strategy    SelectionStrategy
+	backoff     backoff
+	next        uint64
+	connections []driverConnection
+	channels    map[driverConnection]*atomic.Int64
+	draining    map[driverConnection]int
+	drain       time.Duration
+	lifetime    time.Duration
+	jitter      time.Duration
+	opens       atomic.Int64
+	done        chan struct{}
+	stop        chan struct{}
+	stopping    bool
 	closed      bool
 }
 
@@ -34,50 +77,115 @@ func newPool(options *clientOptions) (Client, error) {
 		dialer:   options.dialer,
 		observer: options.observer,
 		logger:   options.logger,
+		metrics:  options.metrics,
+		strategy: options.strategy,
+		backoff:  options.backoff,
+		channels: map[driverConnection]*atomic.Int64{},
+		draining: map[driverConnection]int{},
+		drain:    options.drain,
+		lifetime: options.lifetime,
+		jitter:   options.jitter,
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
 	}
 
-	instance.connections = []*amqp.Connection{}
+	if options.autoscaling != nil {
+		options.capacity = options.autoscaling.clamp(options.capacity)
+	}
 
-	for i := 0; i < options.capacity; i++ {
-		err := instance.newConnection()
-		if err != nil {
-			return nil, err
+	instance.connections = make([]driverConnection, options.capacity)
+
+	// In lazy mode, every connection is opened in the background.
+	if options.lazy {
+		for i := range instance.connections {
+			go instance.retryConnection(i, false)
 		}
+		instance.start(options)
+		return instance, nil
+	}
+
+	minimum := options.minimum
+	if minimum == 0 {
+		minimum = options.capacity
+	}
+	if minimum > options.capacity {
+		return nil, errors.Wrap(ErrInvalidMinConnections, ErrMessageCannotCreateClient)
 	}
 
+	err := instance.open(minimum, options.parallel, options.deadline)
+	if err != nil {
+		// The connections opened before the failure are closed, and their close errors are returned too.
+		list := errorList{}
+		list.add(err)
+		list.add(instance.Close())
+		return nil, list.err()
+	}
+
+	instance.start(options)
 	return instance, nil
 }
 
-// newConnection add a new connection on the connections pool.
-func (e *Pool) newConnection() error {
+// start starts the background routines of the connections pool, once it's created.
+func (e *Pool) start(options *clientOptions) {
+	if options.autoscaling != nil {
+		go e.autoscale(*options.autoscaling)
+	}
+}
+
+// addConnection adds a connection opened at startup on the given slot of the connections pool.
+func (e *Pool) addConnection(idx int, connection driverConnection) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
-	idx := len(e.connections)
-	connection, err := e.dialer.dial(idx)
-	if err != nil {
-		e.logger.Error("Failed to obtain a connection")
-		return errors.Wrap(err, ErrMessageCannotOpenConnection)
-	}
+	e.logger.Debug("Opened connection",
+		SlotField(idx), AddressField(connection.LocalAddr()), URIField(e.dialer.uri(idx)))
+	e.metrics.OnConnectionOpen()
+	e.register(idx, connection)
+}
 
-	e.logger.Debug(fmt.Sprintf("Opened connection #%d (%s)", idx, connection.LocalAddr()))
-	e.connections = append(e.connections, connection)
+// register sets given connection on the given slot of the connections pool, and watches its close and lifetime.
+// It must be called with the lock held.
+func (e *Pool) register(idx int, connection driverConnection) {
+	e.connections[idx] = connection
+	e.channels[connection] = &atomic.Int64{}
 	e.listenOnCloseConnection(idx, connection)
 
-	return nil
+	if e.lifetime > 0 {
+		e.expireConnection(idx, connection, e.lifetime-time.Duration(rand.Int63n(int64(e.jitter)+1)))
+	}
+}
+
+// dial opens a new connection for given slot.
+func (e *Pool) dial(idx int) (driverConnection, error) {
+	start := time.Now()
+	connection, err := e.dialer.dial(idx)
+	e.metrics.OnDial(time.Since(start), err)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return connection, nil
 }
 
 // releaseConnection remove a connection from the connections pool.
-func (e *Pool) releaseConnection(idx int) {
+// It returns false if the connection was not in the connections pool anymore, such as a drained connection.
+func (e *Pool) releaseConnection(idx int, connection driverConnection) bool {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
+
+	delete(e.channels, connection)
+	if idx >= len(e.connections) || e.connections[idx] != connection {
+		delete(e.draining, connection)
+		return false
+	}
+
 	e.connections[idx] = nil
-	e.logger.Debug(fmt.Sprintf("Released connection #%d", idx))
+	e.logger.Debug("Released connection", SlotField(idx))
+	return true
 }
 
 // listenOnCloseConnection will listen on a connection close event.
 // If a connection is closed, it will release it from the connections pool and will try to create a new one.
-func (e *Pool) listenOnCloseConnection(idx int, connection *amqp.Connection) {
+func (e *Pool) listenOnCloseConnection(idx int, connection driverConnection) {
 	receiver := make(chan *amqp.Error)
 	connection.NotifyClose(receiver)
 
@@ -86,75 +194,145 @@ func (e *Pool) listenOnCloseConnection(idx int, connection *amqp.Connection) {
 		if err != nil {
 			e.observer.OnClose(err)
 		}
+		e.metrics.OnConnectionClose()
 
-		e.releaseConnection(idx)
-		e.retryConnection(idx)
+		if e.releaseConnection(idx, connection) {
+			e.retryConnection(idx, true)
+		}
 	}()
 }
 
-// retryConnection will try to open a new connection, unless the client is closed.
+// retryConnection will try to open a new connection, unless the client is closed or the error is permanent.
 // If it succeed, it will add this connection on the connections pool.
-func (e *Pool) retryConnection(idx int) {
-	e.logger.Debug(fmt.Sprintf("Retrying to open a new connection #%d", idx))
+// A reconnect replaces a lost connection, instead of filling a slot left empty at startup.
+func (e *Pool) retryConnection(idx int, reconnect bool) {
+	e.logger.Debug("Retrying to open a new connection", SlotField(idx))
 
-	for {
+	for attempt := 1; ; attempt++ {
 		e.mutex.RLock()
 		closed := e.closed
+		removed := idx >= len(e.connections)
 		e.mutex.RUnlock()
 
-		// If client is closed, cancel retry.
-		if closed {
+		// If client is closed, or if the slot has been removed by a resize, cancel retry.
+		if closed || removed {
 			return
 		}
 
 		// Try to open a new connection.
-		connection, err := e.dialer.dial(idx)
+		connection, err := e.dial(idx)
 		if err == nil {
-			e.mutex.Lock()
-			e.logger.Debug(fmt.Sprintf("Opened new connection #%d (%s)", idx, connection.LocalAddr()))
-			e.connections[idx] = connection
-			e.listenOnCloseConnection(idx, connection)
-			e.mutex.Unlock()
+			e.storeConnection(idx, connection, attempt, reconnect)
+			return
+		}
+
+		e.logger.Warn("Failed to open a new connection",
+			SlotField(idx), URIField(e.dialer.uri(idx)), ErrorField(err), AttemptField(attempt))
+
+		// If error is permanent, such as an authentication failure, cancel retry.
+		if !IsRetryable(err) {
+			e.logger.Error("Cannot open a new connection, stop retrying",
+				SlotField(idx), URIField(e.dialer.uri(idx)), ErrorField(err), AttemptField(attempt))
+			e.observer.OnError(errors.Wrap(err, ErrMessageCannotOpenConnection))
 			return
 		}
 
-		// Schedule a retry between 200ms and 1s.
-		retry := time.Duration((200 + rand.Intn(801))) * time.Millisecond
-		time.Sleep(retry)
+		// Schedule a retry within the backoff range.
+		time.Sleep(e.backoff.delay())
+	}
+}
+
+// storeConnection adds a connection opened in the background on the given slot of the connections pool.
+// If the client has been closed or the slot removed in the meantime, the connection is closed instead.
+func (e *Pool) storeConnection(idx int, connection driverConnection, attempt int, reconnect bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closed || idx >= len(e.connections) || e.connections[idx] != nil {
+		e.close(connection)
+		return
+	}
+
+	e.logger.Debug("Opened new connection",
+		SlotField(idx), AddressField(connection.LocalAddr()), URIField(e.dialer.uri(idx)), AttemptField(attempt))
+	e.metrics.OnConnectionOpen()
+	if reconnect {
+		e.metrics.OnReconnect()
 	}
+	e.register(idx, connection)
 }
 
 // Channel returns a new channel from our connections pool.
-func (e *Pool) Channel() (*amqp.Channel, error) {
+func (e *Pool) Channel() (Channel, error) {
+	start := time.Now()
+
 	e.mutex.RLock()
 	capacity := len(e.connections)
-	offset := rand.Intn(capacity)
+	offset := e.offset(capacity)
 	e.mutex.RUnlock()
 
 	for i := 0; i < capacity; i++ {
 		idx := (i + offset) % capacity
-
-		e.mutex.RLock()
-		connection := e.connections[idx]
-		closed := e.closed
-		e.mutex.RUnlock()
+		connection, channels, closed := e.slot(idx)
 
 		if closed {
+			e.metrics.OnChannelOpen(ErrClientClosed)
 			return nil, errors.Wrap(ErrClientClosed, ErrMessageCannotOpenChannel)
 		}
 
 		if connection != nil {
+			wait := time.Since(start)
 			channel, err := connection.Channel()
 			if err == nil {
-				e.logger.Debug(fmt.Sprintf("Opened channel on connection #%d (%s)", idx, connection.LocalAddr()))
+				e.logger.Debug("Opened channel", SlotField(idx), AddressField(connection.LocalAddr()))
+				e.track(channel, channels)
+				e.metrics.OnConnectionWait(wait)
+				e.metrics.OnChannelOpen(nil)
 				return channel, nil
 			}
 		}
 	}
 
+	e.metrics.OnConnectionWait(time.Since(start))
+	e.metrics.OnChannelOpen(ErrNoConnectionAvailable)
+
 	return nil, errors.Wrap(ErrNoConnectionAvailable, ErrMessageCannotOpenChannel)
 }
 
+// slot returns the connection of the given slot and its open channels counter, if any, and if the pool is closed
+// or shutting down.
+func (e *Pool) slot(idx int) (driverConnection, *atomic.Int64, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	// The slot may have been removed by a resize.
+	if idx >= len(e.connections) || e.connections[idx] == nil {
+		return nil, nil, e.closed || e.stopping
+	}
+
+	connection := e.connections[idx]
+	return connection, e.channels[connection], e.closed || e.stopping
+}
+
+// track counts given channel as open on its connection until it's closed, so a connection is only drained once it
+// has no open channels.
+func (e *Pool) track(channel Channel, channels *atomic.Int64) {
+	e.opens.Add(1)
+	trackChannel(channel, channels)
+}
+
+// offset returns the slot of the first connection to try, according to the selection strategy.
+func (e *Pool) offset(capacity int) int {
+	switch e.strategy {
+	case SelectionRoundRobin:
+		return int((atomic.AddUint64(&e.next, 1) - 1) % uint64(capacity))
+	case SelectionFirstAvailable:
+		return 0
+	default:
+		return rand.Intn(capacity)
+	}
+}
+
 // IsClosed returns if the pool is closed.
 func (e *Pool) IsClosed() bool {
 	e.mutex.RLock()
@@ -163,7 +341,15 @@ func (e *Pool) IsClosed() bool {
 }
 
 // Close will closes all remaining connections and marks it as closed.
+// It returns a ConnectionCloseError for each connection which cannot be closed.
+// Use Shutdown to wait for the borrowed channels to be closed first.
 func (e *Pool) Close() error {
+	return e.closeConnections()
+}
+
+// closeConnections closes all remaining connections, including the drained ones, and marks the pool as closed.
+// It returns a ConnectionCloseError for each connection which cannot be closed.
+func (e *Pool) closeConnections() error {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
@@ -172,16 +358,26 @@ func (e *Pool) Close() error {
 		return nil
 	}
 
+	e.halt()
 	e.closed = true
+	close(e.done)
+
+	list := errorList{}
 	for i := range e.connections {
 		if e.connections[i] != nil {
 			connection := e.connections[i]
-			e.logger.Debug(fmt.Sprintf("Closing connection #%d (%s)", i, connection.LocalAddr()))
-			e.close(connection)
+			e.logger.Debug("Closing connection", SlotField(i), AddressField(connection.LocalAddr()))
+			list.add(e.closeSlot(i, connection))
 		}
 	}
 
-	return nil
+	for connection, idx := range e.draining {
+		e.logger.Debug("Closing drained connection", SlotField(idx), AddressField(connection.LocalAddr()))
+		list.add(e.closeSlot(idx, connection))
+	}
+	e.draining = map[driverConnection]int{}
+
+	return list.err()
 }
 
 // Length returns connections pool capacity.
@@ -198,4 +394,16 @@ func (e *Pool) close(connection io.Closer) {
 	}
 }
 
+// closeSlot closes the connection of given slot, and returns its error, if any, once given to the observer.
+func (e *Pool) closeSlot(idx int, connection driverConnection) error {
+	err := closeOpenConnection(connection)
+	if err != nil {
+		e.logger.Error("Failed to close connection",
+			SlotField(idx), AddressField(connection.LocalAddr()), ErrorField(err))
+		e.observer.OnClose(err)
+		return &ConnectionCloseError{Slot: idx, Address: addressString(connection.LocalAddr()), Err: err}
+	}
+	return nil
+}
+
 var _ Client = (*Pool)(nil)
@@ -0,0 +1,63 @@
+package amqpx
+
+import (
+	"time"
+)
+
+// expireConnection schedules the renewal of the connection of given slot after given delay.
+func (e *Pool) expireConnection(idx int, connection driverConnection, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		e.renewConnection(idx, connection)
+	})
+}
+
+// holds returns if given connection is still the one of given slot, and the pool is neither closed nor shutting down.
+func (e *Pool) holds(idx int, connection driverConnection) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.held(idx, connection)
+}
+
+// held is like holds, but it must be called with the lock held.
+func (e *Pool) held(idx int, connection driverConnection) bool {
+	return !e.closed && !e.stopping && idx < len(e.connections) && e.connections[idx] == connection
+}
+
+// renewConnection replaces the connection of given slot once its lifetime has expired.
+// The new connection is opened first, then the old one is drained, so its channels can finish.
+// If the new connection cannot be opened, the old one is kept and its renewal is scheduled again.
+func (e *Pool) renewConnection(idx int, connection driverConnection) {
+	// The connection may have been lost, removed by a resize, or the pool closed in the meantime.
+	if !e.holds(idx, connection) {
+		return
+	}
+
+	replacement, err := e.dial(idx)
+	if err != nil {
+		e.logger.Warn("Failed to renew connection", SlotField(idx), URIField(e.dialer.uri(idx)), ErrorField(err))
+		delay := e.lifetime
+		if IsRetryable(err) {
+			delay = e.backoff.delay()
+		}
+		e.expireConnection(idx, connection, delay)
+		return
+	}
+
+	e.mutex.Lock()
+	if !e.held(idx, connection) {
+		e.mutex.Unlock()
+		e.close(replacement)
+		return
+	}
+
+	e.logger.Debug("Renewed connection", SlotField(idx), AddressField(replacement.LocalAddr()),
+		URIField(e.dialer.uri(idx)), Field{Key: "previous", Value: AddressField(connection.LocalAddr()).Value})
+	e.metrics.OnConnectionOpen()
+
+	channels := e.channels[connection]
+	e.draining[connection] = idx
+	e.register(idx, replacement)
+	e.mutex.Unlock()
+
+	go e.drainConnection(idx, connection, channels)
+}
@@ -0,0 +1,172 @@
+package amqpx
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Resize default configuration.
+const (
+	// DefaultDrainTimeout is the default time a connection removed by a resize waits for its channels to be closed.
+	DefaultDrainTimeout = 30 * time.Second
+
+	// DefaultAutoscalingInterval is the default time between two evaluations of an autoscaling policy.
+	DefaultAutoscalingInterval = 10 * time.Second
+
+	// drainInterval is the time between two checks of the open channels of a drained connection.
+	drainInterval = 50 * time.Millisecond
+)
+
+// AutoscalingPolicy defines how the connections pool is resized according to its traffic.
+// The capacity is the number of connections required to reach the targets, within Min and Max.
+// If both targets are defined, the highest capacity is used.
+type AutoscalingPolicy struct {
+	// Min is the minimum capacity of the connections pool.
+	Min int
+	// Max is the maximum capacity of the connections pool.
+	Max int
+	// Interval is the time between two evaluations of the policy. It's DefaultAutoscalingInterval if empty.
+	Interval time.Duration
+	// ChannelsPerConnection is the target number of open channels per connection.
+	ChannelsPerConnection int
+	// ChannelOpenRate is the target number of channels opened per second and per connection.
+	ChannelOpenRate float64
+}
+
+// validate returns an error if the policy is invalid.
+func (e AutoscalingPolicy) validate() error {
+	if e.Min <= 0 || e.Max < e.Min || e.Interval < 0 {
+		return ErrInvalidAutoscalingPolicy
+	}
+	if e.ChannelsPerConnection < 0 || e.ChannelOpenRate < 0 {
+		return ErrInvalidAutoscalingPolicy
+	}
+	if e.ChannelsPerConnection == 0 && e.ChannelOpenRate == 0 {
+		return ErrInvalidAutoscalingPolicy
+	}
+	return nil
+}
+
+// capacity returns the capacity required for given number of open channels and channel open rate.
+func (e AutoscalingPolicy) capacity(channels int64, rate float64) int {
+	capacity := 0
+	if e.ChannelsPerConnection > 0 {
+		capacity = int(math.Ceil(float64(channels) / float64(e.ChannelsPerConnection)))
+	}
+	if e.ChannelOpenRate > 0 {
+		capacity = max(capacity, int(math.Ceil(rate/e.ChannelOpenRate)))
+	}
+	return e.clamp(capacity)
+}
+
+// clamp returns given capacity within the policy bounds.
+func (e AutoscalingPolicy) clamp(capacity int) int {
+	return min(max(capacity, e.Min), e.Max)
+}
+
+// Resize changes the number of connections of the pool.
+// New connections are opened in the background, like lost connections. Removed connections are drained: they are
+// closed once their channels are closed, or once the drain timeout is reached.
+func (e *Pool) Resize(capacity int) error {
+	if capacity <= 0 {
+		return ErrInvalidConnectionsPoolCapacity
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closed || e.stopping {
+		return errors.Wrap(ErrClientClosed, ErrMessageCannotResizePool)
+	}
+
+	length := len(e.connections)
+	if capacity == length {
+		return nil
+	}
+
+	e.logger.Info("Resizing connections pool",
+		Field{Key: "from", Value: length}, Field{Key: "to", Value: capacity})
+
+	for idx := length; idx < capacity; idx++ {
+		e.connections = append(e.connections, nil)
+		go e.retryConnection(idx, false)
+	}
+
+	for idx := capacity; idx < length; idx++ {
+		connection := e.connections[idx]
+		if connection != nil {
+			e.draining[connection] = idx
+			go e.drainConnection(idx, connection, e.channels[connection])
+		}
+	}
+	if capacity < length {
+		e.connections = e.connections[:capacity]
+	}
+
+	return nil
+}
+
+// drainConnection waits for the channels of a connection removed by a resize to be closed, then closes it.
+// If the pool is closed in the meantime, the connection is closed by the pool.
+func (e *Pool) drainConnection(idx int, connection driverConnection, channels *atomic.Int64) {
+	e.logger.Debug("Draining connection", SlotField(idx), AddressField(connection.LocalAddr()))
+
+	deadline := time.Now().Add(e.drain)
+	for channels.Load() > 0 && time.Now().Before(deadline) && !connection.IsClosed() {
+		select {
+		case <-e.done:
+			return
+		case <-time.After(drainInterval):
+		}
+	}
+
+	e.mutex.Lock()
+	_, ok := e.draining[connection]
+	delete(e.draining, connection)
+	e.mutex.Unlock()
+
+	if ok && !connection.IsClosed() {
+		e.logger.Debug("Closing drained connection", SlotField(idx), AddressField(connection.LocalAddr()),
+			Field{Key: "channels", Value: channels.Load()})
+		e.close(connection)
+	}
+}
+
+// autoscale evaluates given policy at each interval, and resizes the pool accordingly until it's shutting down.
+func (e *Pool) autoscale(policy AutoscalingPolicy) {
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case now := <-ticker.C:
+			rate := float64(e.opens.Swap(0)) / now.Sub(last).Seconds()
+			last = now
+
+			err := e.Resize(policy.capacity(e.openChannels(), rate))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// openChannels returns the number of open channels on the connections of the pool.
+func (e *Pool) openChannels() int64 {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	total := int64(0)
+	for _, connection := range e.connections {
+		if connection != nil {
+			total += e.channels[connection].Load()
+		}
+	}
+	return total
+}
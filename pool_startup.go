@@ -0,0 +1,240 @@
+package amqpx
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultStartupParallelism is the default number of connections dialed concurrently at startup.
+const DefaultStartupParallelism = 10
+
+// dialResult is the outcome of a connection dialed at startup.
+type dialResult struct {
+	idx        int
+	connection driverConnection
+	err        error
+}
+
+// nodeFailure aggregates the startup failures of a broker node.
+type nodeFailure struct {
+	uri    string
+	failed int
+	total  int
+	err    error
+}
+
+// error returns the failure as an error, with the first error of the node.
+// If the startup was aborted, the other connections of the node were still dialing, or not dialed at all.
+func (e *nodeFailure) error(aborted bool) error {
+	if aborted {
+		return errors.Wrapf(e.err, "%s: %d of %d connections failed before startup was aborted",
+			RedactURI(e.uri), e.failed, e.total)
+	}
+	return errors.Wrapf(e.err, "%s: %d of %d connections failed", RedactURI(e.uri), e.failed, e.total)
+}
+
+// startup tracks the connections dialed concurrently when the connections pool is created.
+type startup struct {
+	pool     *Pool
+	minimum  int
+	opened   int
+	failed   int
+	received []bool
+	pending  []int
+	nodes    []*nodeFailure
+	aborted  bool
+}
+
+// newStartup returns a startup for every slot of the given pool.
+func newStartup(pool *Pool, minimum int) *startup {
+	instance := &startup{
+		pool:     pool,
+		minimum:  minimum,
+		received: make([]bool, len(pool.connections)),
+	}
+
+	indexes := map[string]*nodeFailure{}
+	for idx := range pool.connections {
+		uri := pool.dialer.uri(idx)
+		node, ok := indexes[uri]
+		if !ok {
+			node = &nodeFailure{uri: uri}
+			indexes[uri] = node
+			instance.nodes = append(instance.nodes, node)
+		}
+		node.total++
+	}
+
+	return instance
+}
+
+// add records the result of a dial, and returns if the startup cannot succeed anymore.
+func (e *startup) add(result dialResult) bool {
+	e.received[result.idx] = true
+
+	if result.err == nil {
+		e.opened++
+		e.pool.addConnection(result.idx, result.connection)
+		return false
+	}
+
+	e.pool.logger.Error("Failed to obtain a connection",
+		SlotField(result.idx), URIField(e.pool.dialer.uri(result.idx)), ErrorField(result.err))
+	e.fail(result.idx, result.err)
+	e.pending = append(e.pending, result.idx)
+
+	// Stop as soon as the minimum cannot be reached, or on a permanent error.
+	return !IsRetryable(result.err) || len(e.received)-e.failed < e.minimum
+}
+
+// expire records the slots still dialing when the startup deadline is reached.
+func (e *startup) expire(timeout time.Duration) {
+	for idx, received := range e.received {
+		if !received {
+			e.fail(idx, &Error{Kind: ErrTimeout, Err: errors.Wrapf(ErrStartupTimeout, "%s", timeout), retryable: true})
+		}
+	}
+}
+
+// fail records a failure for the node of given slot.
+func (e *startup) fail(idx int, err error) {
+	e.failed++
+
+	uri := e.pool.dialer.uri(idx)
+	for _, node := range e.nodes {
+		if node.uri == uri {
+			node.failed++
+			if node.err == nil {
+				node.err = err
+			}
+			return
+		}
+	}
+}
+
+// succeeded returns if the minimum number of connections is opened.
+func (e *startup) succeeded() bool {
+	return e.opened >= e.minimum
+}
+
+// err returns the failures aggregated per node.
+func (e *startup) err() error {
+	list := errorList{}
+	for _, node := range e.nodes {
+		if node.failed > 0 {
+			list.add(node.error(e.aborted))
+		}
+	}
+
+	return errors.Wrap(list.err(), ErrMessageCannotOpenConnection)
+}
+
+// open dials every connection of the pool concurrently, and succeeds if at least the given minimum is opened before
+// the startup timeout, if any.
+// The connections that failed with a retryable error, or that are still dialing, are then opened in the background.
+func (e *Pool) open(minimum int, parallelism int, timeout time.Duration) error {
+	capacity := len(e.connections)
+	results, abort := e.dispatch(parallelism)
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	state := newStartup(e, minimum)
+	for received := 0; received < capacity; received++ {
+		select {
+		case result := <-results:
+			if state.add(result) {
+				close(abort)
+				remaining := capacity - received - 1
+				state.aborted = remaining > 0
+				go e.settle(results, remaining, false)
+				return state.err()
+			}
+
+		case <-deadline:
+			state.expire(timeout)
+			if !state.succeeded() {
+				close(abort)
+				go e.settle(results, capacity-received, false)
+				return state.err()
+			}
+
+			e.logger.Warn("Startup timeout is reached, opening remaining connections in the background",
+				Field{Key: "opened", Value: state.opened})
+			go e.settle(results, capacity-received, true)
+			return e.retrySlots(state)
+		}
+	}
+
+	return e.retrySlots(state)
+}
+
+// dispatch dials every slot of the connections pool with the given number of workers.
+// Closing the returned channel aborts the dials not started yet.
+func (e *Pool) dispatch(parallelism int) (<-chan dialResult, chan struct{}) {
+	capacity := len(e.connections)
+
+	slots := make(chan int, capacity)
+	for i := 0; i < capacity; i++ {
+		slots <- i
+	}
+	close(slots)
+
+	abort := make(chan struct{})
+	results := make(chan dialResult, capacity)
+	for i := 0; i < parallelism && i < capacity; i++ {
+		go e.dialSlots(slots, results, abort)
+	}
+
+	return results, abort
+}
+
+// dialSlots dials the given slots, until the startup is aborted.
+func (e *Pool) dialSlots(slots <-chan int, results chan<- dialResult, abort <-chan struct{}) {
+	for idx := range slots {
+		select {
+		case <-abort:
+			results <- dialResult{idx: idx, err: ErrClientClosed}
+			continue
+		default:
+		}
+
+		connection, err := e.dial(idx)
+		results <- dialResult{idx: idx, connection: connection, err: err}
+	}
+}
+
+// settle handles the given number of dials completed after the end of the startup.
+// Their connections are added on the connections pool, unless it's closed in the meantime.
+// If retry is enabled, the failed ones are opened in the background.
+func (e *Pool) settle(results <-chan dialResult, remaining int, retry bool) {
+	for i := 0; i < remaining; i++ {
+		result := <-results
+		if result.err == nil {
+			e.storeConnection(result.idx, result.connection, 1, false)
+			continue
+		}
+		if retry && IsRetryable(result.err) {
+			go e.retryConnection(result.idx, false)
+		}
+	}
+}
+
+// retrySlots opens in the background the connections which have failed during a successful startup.
+func (e *Pool) retrySlots(state *startup) error {
+	for _, idx := range state.pending {
+		go e.retryConnection(idx, false)
+	}
+
+	if state.failed > 0 {
+		e.logger.Warn("Started with missing connections",
+			Field{Key: "opened", Value: state.opened}, ErrorField(state.err()))
+	}
+
+	return nil
+}
@@ -1,11 +1,16 @@
 package amqpx_test
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/ulule/amqpx"
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
 )
 
 func TestPoolClient(t *testing.T) {
@@ -38,7 +43,7 @@ func TestPoolClient_WithInvalidBrokerURI(t *testing.T) {
 	client, err := amqpx.New(dialer, amqpx.WithCapacity(capacity))
 	is.Error(err)
 	is.Nil(client)
-	is.Contains(err.Error(), amqpx.ErrMessageDialTimeout)
+	is.Contains(err.Error(), amqpx.ErrMessageDialRefused)
 	is.Contains(err.Error(), amqpx.ErrMessageCannotOpenConnection)
 }
 
@@ -94,3 +99,432 @@ func TestPoolClient_ConcurrentAccess(t *testing.T) {
 	is.NoError(client.Close())
 	wg.Wait()
 }
+
+func TestPoolClient_WithSelectionStrategy(t *testing.T) {
+	is := NewRunner(t)
+
+	first := amqpxtest.NewBroker()
+	second := amqpxtest.NewBroker()
+
+	dialer, err := amqpx.ClusterDialer([]string{NewServer(t, first).URI(), NewServer(t, second).URI()})
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithSelectionStrategy(99))
+	is.Error(err)
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidSelectionStrategy, errors.Cause(err))
+
+	for _, scenario := range []struct {
+		strategy amqpx.SelectionStrategy
+		first    int
+		second   int
+	}{
+		{strategy: amqpx.SelectionFirstAvailable, first: 4, second: 0},
+		{strategy: amqpx.SelectionRoundRobin, first: 2, second: 2},
+	} {
+		client, err := amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithSelectionStrategy(scenario.strategy))
+		is.NoError(err)
+
+		for i := 0; i < 4; i++ {
+			channel, err := client.Channel()
+			is.NoError(err)
+			_, err = channel.QueueDeclare(fmt.Sprintf("selection.%d.%d", scenario.strategy, i),
+				false, false, false, false, nil)
+			is.NoError(err)
+			is.NoError(channel.Close())
+		}
+		is.NoError(client.Close())
+
+		count := func(broker *amqpxtest.Broker) int {
+			total := 0
+			for i := 0; i < 4; i++ {
+				_, ok := broker.Queue(fmt.Sprintf("selection.%d.%d", scenario.strategy, i))
+				if ok {
+					total++
+				}
+			}
+			return total
+		}
+		is.Equal(scenario.first, count(first))
+		is.Equal(scenario.second, count(second))
+	}
+}
+
+func TestPoolClient_WithMinConnections(t *testing.T) {
+	is := NewRunner(t)
+
+	broker := amqpxtest.NewBroker()
+	server := NewServer(t, broker)
+	other := NewServer(t, broker)
+
+	cluster, err := amqpx.ClusterDialer([]string{server.URI(), other.URI()})
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(cluster)
+	is.NoError(err)
+	dialer.Partition(other.Addr())
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(4))
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithMinConnections(3))
+	is.Error(err)
+	is.Nil(client)
+	is.Eventually(func() bool {
+		return server.Connections() == 0 && dialer.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithMinConnections(5))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidMinConnections, errors.Cause(err))
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithMinConnections(2),
+		amqpx.WithReconnectBackoff(10*time.Millisecond, 20*time.Millisecond))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+	is.Equal(2, dialer.Connections())
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+
+	dialer.Heal()
+	is.Eventually(func() bool {
+		return dialer.Connections() == 4 && other.Connections() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestPoolClient_WithLazyConnections(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	dialer.Refuse(true)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithLazyConnections(),
+		amqpx.WithReconnectBackoff(10*time.Millisecond, 20*time.Millisecond))
+	is.NoError(err)
+
+	_, err = client.Channel()
+	is.Equal(amqpx.ErrNoConnectionAvailable, errors.Cause(err))
+
+	dialer.Refuse(false)
+	is.Eventually(func() bool {
+		return server.Connections() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+	is.NoError(client.Close())
+
+	is.Eventually(func() bool {
+		return server.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestPoolClient_WithStartupParallelism(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	dialer.SetLatency(200 * time.Millisecond)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithStartupParallelism(0))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidStartupParallelism, errors.Cause(err))
+
+	start := time.Now()
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithStartupParallelism(4))
+	is.NoError(err)
+	is.True(time.Since(start) < 600*time.Millisecond)
+	is.Equal(4, server.Connections())
+	is.NoError(client.Close())
+
+	start = time.Now()
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithStartupParallelism(2))
+	is.NoError(err)
+	is.True(time.Since(start) >= 400*time.Millisecond)
+	is.NoError(client.Close())
+}
+
+func TestPoolClient_WithStartupTimeout(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	dialer.SetLatency(500 * time.Millisecond)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithStartupTimeout(0))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidStartupTimeout, errors.Cause(err))
+
+	start := time.Now()
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithStartupTimeout(100*time.Millisecond))
+	is.Error(err)
+	is.Nil(client)
+	is.True(time.Since(start) < 400*time.Millisecond)
+	is.True(errors.Is(err, amqpx.ErrStartupTimeout))
+	is.True(errors.Is(err, amqpx.ErrTimeout))
+	is.True(amqpx.IsRetryable(err))
+	is.Contains(err.Error(), "2 of 2 connections failed")
+	is.Eventually(func() bool {
+		return dialer.Dials() == 2 && dialer.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithLazyConnections(),
+		amqpx.WithStartupTimeout(100*time.Millisecond))
+	is.NoError(err)
+	is.NoError(client.Close())
+
+	dialer.SetLatency(0)
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithStartupTimeout(time.Second))
+	is.NoError(err)
+	is.Equal(2, server.Connections())
+	is.NoError(client.Close())
+}
+
+func TestPoolClient_StartupErrors(t *testing.T) {
+	is := NewRunner(t)
+
+	broker := amqpxtest.NewBroker()
+	server := NewServer(t, broker)
+	other := NewServer(t, broker)
+
+	cluster, err := amqpx.ClusterDialer([]string{server.URI(), other.URI()})
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(cluster)
+	is.NoError(err)
+	dialer.Partition(server.Addr(), other.Addr())
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithMinConnections(1))
+	is.Error(err)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+	is.Contains(err.Error(), fmt.Sprintf("%s: 2 of 2 connections failed", amqpx.RedactURI(server.URI())))
+	is.Contains(err.Error(), fmt.Sprintf("%s: 2 of 2 connections failed", amqpx.RedactURI(other.URI())))
+
+	// Without a minimum, the startup is aborted on the first failure.
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(4), amqpx.WithStartupParallelism(1))
+	is.Error(err)
+	is.Nil(client)
+	is.Contains(err.Error(), fmt.Sprintf("%s: 1 of 2 connections failed before startup was aborted",
+		amqpx.RedactURI(server.URI())))
+	is.False(strings.Contains(err.Error(), amqpx.RedactURI(other.URI())))
+}
+
+func TestPoolClient_Resize(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1), amqpx.WithSelectionStrategy(amqpx.SelectionRoundRobin))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	pool, ok := client.(*amqpx.Pool)
+	is.True(ok)
+	is.Equal(amqpx.ErrInvalidConnectionsPoolCapacity, pool.Resize(0))
+
+	is.NoError(pool.Resize(2))
+	is.Equal(2, pool.Length())
+	is.Eventually(func() bool {
+		return server.Connections() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	first, err := pool.Channel()
+	is.NoError(err)
+	second, err := pool.Channel()
+	is.NoError(err)
+
+	is.NoError(pool.Resize(1))
+	is.Equal(1, pool.Length())
+
+	// The removed connection is kept until its channels are closed.
+	time.Sleep(200 * time.Millisecond)
+	is.Equal(2, server.Connections())
+	for _, channel := range []amqpx.Channel{first, second} {
+		_, err = channel.QueueDeclare("resize", false, false, false, false, nil)
+		is.NoError(err)
+	}
+
+	is.NoError(second.Close())
+	is.Eventually(func() bool {
+		return server.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	is.False(first.IsClosed())
+	is.NoError(first.Close())
+
+	for i := 0; i < 4; i++ {
+		channel, err := pool.Channel()
+		is.NoError(err)
+		is.NoError(channel.Close())
+	}
+}
+
+func TestPoolClient_WithDrainTimeout(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithDrainTimeout(0))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidDrainTimeout, errors.Cause(err))
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(2), amqpx.WithDrainTimeout(200*time.Millisecond),
+		amqpx.WithSelectionStrategy(amqpx.SelectionRoundRobin))
+	is.NoError(err)
+
+	first, err := client.Channel()
+	is.NoError(err)
+	second, err := client.Channel()
+	is.NoError(err)
+
+	is.NoError(client.(*amqpx.Pool).Resize(1))
+	is.Eventually(func() bool {
+		return server.Connections() == 1 && second.IsClosed()
+	}, 5*time.Second, 20*time.Millisecond)
+	is.False(first.IsClosed())
+
+	is.NoError(client.Close())
+	is.Error(client.(*amqpx.Pool).Resize(2))
+}
+
+func TestPoolClient_WithAutoscaling(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithAutoscaling(amqpx.AutoscalingPolicy{Min: 2, Max: 1}))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidAutoscalingPolicy, errors.Cause(err))
+
+	client, err = amqpx.New(dialer, amqpx.WithAutoscaling(amqpx.AutoscalingPolicy{Min: 1, Max: 4}))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidAutoscalingPolicy, errors.Cause(err))
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(10), amqpx.WithAutoscaling(amqpx.AutoscalingPolicy{
+		Min:                   1,
+		Max:                   3,
+		Interval:              50 * time.Millisecond,
+		ChannelsPerConnection: 2,
+	}))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+	pool := client.(*amqpx.Pool)
+	is.Equal(3, pool.Length())
+
+	is.Eventually(func() bool {
+		return pool.Length() == 1 && server.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	channels := []amqpx.Channel{}
+	for i := 0; i < 10; i++ {
+		channel, err := client.Channel()
+		is.NoError(err)
+		channels = append(channels, channel)
+	}
+
+	is.Eventually(func() bool {
+		return pool.Length() == 3 && server.Connections() == 3
+	}, 5*time.Second, 20*time.Millisecond)
+
+	for _, channel := range channels {
+		is.NoError(channel.Close())
+	}
+
+	is.Eventually(func() bool {
+		return pool.Length() == 1 && server.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestPoolClient_WithConnectionMaxLifetime(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithConnectionMaxLifetime(time.Second, time.Second))
+	is.Nil(client)
+	is.Equal(amqpx.ErrInvalidConnectionMaxLifetime, errors.Cause(err))
+
+	client, err = amqpx.New(dialer, amqpx.WithCapacity(1), amqpx.WithConnectionMaxLifetime(time.Second, 0))
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	// The new connection is opened first, and the old one is kept while its channel is open.
+	is.Eventually(func() bool {
+		return dialer.Dials() == 2 && server.Connections() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	_, err = channel.QueueDeclare("lifetime", false, false, false, false, nil)
+	is.NoError(err)
+
+	other, err := client.Channel()
+	is.NoError(err)
+	is.NoError(other.Close())
+
+	is.NoError(channel.Close())
+	is.Eventually(func() bool {
+		return server.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	is.Eventually(func() bool {
+		return dialer.Dials() >= 3
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestPoolClient_CloseTwice(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2))
+	is.NoError(err)
+
+	is.NoError(client.Close())
+	is.True(client.IsClosed())
+	is.Eventually(func() bool {
+		return server.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Closing the pool again is a no-op.
+	is.NoError(client.Close())
+}
@@ -0,0 +1,123 @@
+package amqpx
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Publisher publishes messages of type T on an exchange using a Client.
+// Messages are encoded with the Publisher's codec, which is JSON by default.
+type Publisher[T any] struct {
+	publisherOptions
+	client     Client
+	exchange   string
+	routingKey string
+	publish    PublishFunc
+}
+
+// NewPublisher returns a new Publisher bound to given exchange and routing key.
+func NewPublisher[T any](client Client, exchange string, routingKey string,
+	options ...PublisherOption) (*Publisher[T], error) {
+
+	if client == nil {
+		return nil, errors.Wrap(ErrClientRequired, ErrMessageCannotCreatePublisher)
+	}
+
+	opts := newPublisherOptions()
+	for _, option := range options {
+		err := option.apply(&opts)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrMessageCannotCreatePublisher)
+		}
+	}
+
+	publisher := &Publisher[T]{
+		publisherOptions: opts,
+		client:           client,
+		exchange:         exchange,
+		routingKey:       routingKey,
+	}
+	interceptors := make([]PublishInterceptor, 0, len(opts.interceptors)+1)
+	interceptors = append(interceptors, opts.interceptors...)
+	interceptors = append(interceptors, TraceInterceptor(opts.tracer))
+	publisher.publish = ChainPublish(publisher.send, interceptors...)
+
+	return publisher, nil
+}
+
+// Exchange returns the exchange used by publisher.
+func (e *Publisher[T]) Exchange() string {
+	return e.exchange
+}
+
+// RoutingKey returns the routing key used by publisher.
+func (e *Publisher[T]) RoutingKey() string {
+	return e.routingKey
+}
+
+// Publish encodes given message and publishes it using a new channel from client.
+// The message goes through publisher's interceptors before being sent.
+func (e *Publisher[T]) Publish(ctx context.Context, message T) error {
+	body, err := e.codec.Encode(message)
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotEncodeMessage)
+	}
+
+	return e.publish(ctx, e.exchange, e.routingKey, &amqp.Publishing{
+		ContentType:  e.codec.ContentType(),
+		DeliveryMode: e.deliveryMode,
+		Body:         body,
+	})
+}
+
+// send publishes given message using a new channel from client.
+// If publisher uses confirm mode, it waits for the broker confirmation.
+func (e *Publisher[T]) send(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+	err := ctx.Err()
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotPublishMessage)
+	}
+
+	channel, err := e.client.Channel()
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotPublishMessage)
+	}
+	defer func() {
+		thr := channel.Close()
+		_ = thr
+	}()
+
+	var confirms chan amqp.Confirmation
+	if e.confirm {
+		err = channel.Confirm(false)
+		if err != nil {
+			return errors.Wrap(err, ErrMessageCannotPublishMessage)
+		}
+		confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
+	err = channel.PublishWithContext(ctx, exchange, key, e.mandatory, false, *message)
+	if err != nil {
+		return errors.Wrap(err, ErrMessageCannotPublishMessage)
+	}
+
+	if !e.confirm {
+		return nil
+	}
+
+	select {
+	case confirmation, ok := <-confirms:
+		if !ok {
+			return errors.Wrap(ErrPublishUnconfirmed, ErrMessageCannotPublishMessage)
+		}
+		if !confirmation.Ack {
+			return errors.Wrap(ErrPublishNacked, ErrMessageCannotPublishMessage)
+		}
+		return nil
+
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), ErrMessageCannotPublishMessage)
+	}
+}
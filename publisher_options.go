@@ -0,0 +1,93 @@
+package amqpx
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// PublisherOption is used to define Publisher options.
+type PublisherOption interface {
+	apply(*publisherOptions) error
+}
+
+type publisherOption func(*publisherOptions) error
+
+func (o publisherOption) apply(instance *publisherOptions) error {
+	return o(instance)
+}
+
+type publisherOptions struct {
+	codec        Codec
+	tracer       Tracer
+	interceptors []PublishInterceptor
+	mandatory    bool
+	confirm      bool
+	deliveryMode uint8
+}
+
+func newPublisherOptions() publisherOptions {
+	return publisherOptions{
+		codec:        JSONCodec{},
+		tracer:       noopTracer{},
+		deliveryMode: amqp.Persistent,
+	}
+}
+
+// WithPublisherCodec will configure a Publisher with the given codec.
+func WithPublisherCodec(codec Codec) PublisherOption {
+	return publisherOption(func(options *publisherOptions) error {
+		if codec == nil {
+			return ErrCodecRequired
+		}
+		options.codec = codec
+		return nil
+	})
+}
+
+// WithPublisherMandatory will configure a Publisher to publish mandatory messages.
+func WithPublisherMandatory() PublisherOption {
+	return publisherOption(func(options *publisherOptions) error {
+		options.mandatory = true
+		return nil
+	})
+}
+
+// WithPublisherTransient will configure a Publisher to publish transient messages.
+func WithPublisherTransient() PublisherOption {
+	return publisherOption(func(options *publisherOptions) error {
+		options.deliveryMode = amqp.Transient
+		return nil
+	})
+}
+
+// WithPublisherConfirm will configure a Publisher to wait for a broker confirmation of each message.
+func WithPublisherConfirm() PublisherOption {
+	return publisherOption(func(options *publisherOptions) error {
+		options.confirm = true
+		return nil
+	})
+}
+
+// WithPublisherInterceptors will configure a Publisher with the given interceptors.
+// The first interceptor is the outermost one, i.e. it's the first to receive a message.
+func WithPublisherInterceptors(interceptors ...PublishInterceptor) PublisherOption {
+	return publisherOption(func(options *publisherOptions) error {
+		for _, interceptor := range interceptors {
+			if interceptor == nil {
+				return ErrInterceptorRequired
+			}
+		}
+		options.interceptors = append(options.interceptors, interceptors...)
+		return nil
+	})
+}
+
+// WithPublisherTracer will configure a Publisher to inject the trace context of messages using the given tracer.
+func WithPublisherTracer(tracer Tracer) PublisherOption {
+	return publisherOption(func(options *publisherOptions) error {
+		if tracer == nil {
+			return ErrTracerRequired
+		}
+		options.tracer = tracer
+		return nil
+	})
+}
@@ -0,0 +1,111 @@
+package amqpx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func TestPublisher(t *testing.T) {
+	is := NewRunner(t)
+
+	client, err := NewClient()
+	is.NoError(err)
+	is.NotNil(client)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	publisher, err := amqpx.NewPublisher[Event](client, warpExchange, "random.publisher")
+	is.NoError(err)
+	is.NotNil(publisher)
+	is.Equal(warpExchange, publisher.Exchange())
+	is.Equal("random.publisher", publisher.RoutingKey())
+
+	is.NoError(publisher.Publish(context.Background(), Event{Message: "hello"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	is.Error(publisher.Publish(ctx, Event{Message: "hello"}))
+}
+
+func TestPublisher_WithConfirm(t *testing.T) {
+	is := NewRunner(t)
+
+	client, err := NewClient()
+	is.NoError(err)
+	is.NotNil(client)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	results := make(chan amqpx.PublishResult, 1)
+	publisher, err := amqpx.NewPublisher[Event](client, warpExchange, "random.publisher",
+		amqpx.WithPublisherConfirm(),
+		amqpx.WithPublisherInterceptors(
+			amqpx.ResultInterceptor(func(result amqpx.PublishResult) {
+				results <- result
+			}),
+			amqpx.MessageIDInterceptor(nil),
+		),
+	)
+	is.NoError(err)
+
+	is.NoError(publisher.Publish(context.Background(), Event{Message: "hello"}))
+
+	result := <-results
+	is.NoError(result.Err)
+	is.Equal(32, len(result.MessageID))
+}
+
+func TestPublisher_Required(t *testing.T) {
+	is := NewRunner(t)
+
+	publisher, err := amqpx.NewPublisher[Event](nil, warpExchange, "random.publisher")
+	is.Error(err)
+	is.Nil(publisher)
+	is.Equal(amqpx.ErrClientRequired, errors.Cause(err))
+}
+
+// UnconfirmedClient is a client whose channels are closed before confirming published messages.
+type UnconfirmedClient struct {
+	amqpx.Client
+}
+
+func (e UnconfirmedClient) Channel() (amqpx.Channel, error) {
+	channel, err := e.Client.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return UnconfirmedChannel{Channel: channel}, nil
+}
+
+type UnconfirmedChannel struct {
+	amqpx.Channel
+}
+
+func (e UnconfirmedChannel) NotifyPublish(confirms chan amqp.Confirmation) chan amqp.Confirmation {
+	close(confirms)
+	return confirms
+}
+
+func TestPublisher_WithConfirm_ChannelClosed(t *testing.T) {
+	is := NewRunner(t)
+
+	client := UnconfirmedClient{Client: amqpxtest.NewClient(amqpxtest.NewBroker())}
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	publisher, err := amqpx.NewPublisher[Event](client, warpExchange, "random.publisher", amqpx.WithPublisherConfirm())
+	is.NoError(err)
+
+	err = publisher.Publish(context.Background(), Event{Message: "hello"})
+	is.Equal(amqpx.ErrPublishUnconfirmed, errors.Cause(err))
+	is.False(errors.Is(err, amqpx.ErrPublishNacked))
+}
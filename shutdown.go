@@ -0,0 +1,127 @@
+package amqpx
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// shutdownInterval is the time between two checks of the borrowed channels during a shutdown.
+const shutdownInterval = 20 * time.Millisecond
+
+// ShutdownNotifier is implemented by clients which notify the start of their graceful shutdown,
+// so consumers can stop before their channel is closed.
+type ShutdownNotifier interface {
+	// ShuttingDown returns a channel which is closed once the client starts to shut down.
+	ShuttingDown() <-chan struct{}
+}
+
+// waitChannels waits for the given count of borrowed channels to reach zero, until given context is done.
+// If the context is done first, it returns its error with the number of channels still open.
+func waitChannels(ctx context.Context, count func() int64) error {
+	ticker := time.NewTicker(shutdownInterval)
+	defer ticker.Stop()
+
+	for {
+		open := count()
+		if open == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%d channels still open", open)
+		case <-ticker.C:
+		}
+	}
+}
+
+// halt stops handing out channels and notifies the shutdown, unless it's already done.
+// It must be called with the lock held.
+func (e *Pool) halt() {
+	if !e.stopping {
+		e.stopping = true
+		close(e.stop)
+	}
+}
+
+// ShuttingDown implements ShutdownNotifier interface.
+func (e *Pool) ShuttingDown() <-chan struct{} {
+	return e.stop
+}
+
+// Shutdown gracefully closes the pool: it stops handing out channels, waits for the borrowed channels to be closed,
+// such as the ones of consumers, then closes every connection.
+// If given context is done first, the connections are closed anyway, and its error is returned with the ones of
+// the connections.
+func (e *Pool) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return nil
+	}
+	e.halt()
+	e.mutex.Unlock()
+
+	e.logger.Debug("Shutting down connections pool")
+
+	list := errorList{}
+	list.add(waitChannels(ctx, e.borrowedChannels))
+	list.add(e.closeConnections())
+
+	return list.err()
+}
+
+// borrowedChannels returns the number of open channels, including the ones of drained connections.
+func (e *Pool) borrowedChannels() int64 {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	total := int64(0)
+	for _, channels := range e.channels {
+		total += channels.Load()
+	}
+	return total
+}
+
+// ShuttingDown implements ShutdownNotifier interface.
+func (e *Simple) ShuttingDown() <-chan struct{} {
+	return e.stop
+}
+
+// Shutdown gracefully closes the client: it stops handing out channels, waits for the borrowed channels to be closed,
+// such as the ones of consumers, then closes the connection.
+// If given context is done first, the connection is closed anyway, and its error is returned with the one of
+// the connection.
+func (e *Simple) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return nil
+	}
+	e.halt()
+	e.mutex.Unlock()
+
+	e.logger.Debug("Shutting down connection")
+
+	list := errorList{}
+	list.add(waitChannels(ctx, e.channels.Load))
+	list.add(e.closeConnection())
+
+	return list.err()
+}
+
+// halt stops handing out channels and notifies the shutdown, unless it's already done.
+// It must be called with the lock held.
+func (e *Simple) halt() {
+	if !e.stopping {
+		e.stopping = true
+		close(e.stop)
+	}
+}
+
+var (
+	_ ShutdownNotifier = (*Pool)(nil)
+	_ ShutdownNotifier = (*Simple)(nil)
+)
@@ -0,0 +1,144 @@
+package amqpx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func TestShutdown(t *testing.T) {
+	for _, scenario := range []struct {
+		name    string
+		options []amqpx.ClientOption
+	}{
+		{name: "Pool", options: []amqpx.ClientOption{amqpx.WithCapacity(2)}},
+		{name: "Simple", options: []amqpx.ClientOption{amqpx.WithoutConnectionsPool()}},
+	} {
+		t.Run(scenario.name, func(t *testing.T) {
+			is := NewRunner(t)
+
+			server := NewServer(t, amqpxtest.NewBroker())
+			dialer, err := amqpx.SimpleDialer(server.URI())
+			is.NoError(err)
+
+			client, err := amqpx.New(dialer, scenario.options...)
+			is.NoError(err)
+
+			channel, err := client.Channel()
+			is.NoError(err)
+
+			done := make(chan error, 1)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				done <- client.Shutdown(ctx)
+			}()
+
+			select {
+			case <-client.(amqpx.ShutdownNotifier).ShuttingDown():
+			case <-time.After(time.Second):
+				is.Fail("shutdown not notified")
+			}
+
+			_, err = client.Channel()
+			is.Equal(amqpx.ErrClientClosed, errors.Cause(err))
+
+			// The borrowed channel is still usable until it's closed.
+			time.Sleep(100 * time.Millisecond)
+			is.False(client.IsClosed())
+			_, err = channel.QueueDeclare("shutdown", false, false, false, false, nil)
+			is.NoError(err)
+			is.NoError(channel.Close())
+
+			select {
+			case err = <-done:
+				is.NoError(err)
+			case <-time.After(5 * time.Second):
+				is.Fail("shutdown not completed")
+			}
+			is.True(client.IsClosed())
+			is.Eventually(func() bool {
+				return server.Connections() == 0
+			}, 5*time.Second, 20*time.Millisecond)
+
+			is.NoError(client.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestShutdown_Deadline(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(2))
+	is.NoError(err)
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = client.Shutdown(ctx)
+	is.Error(err)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+	is.Contains(err.Error(), "1 channels still open")
+	is.True(client.IsClosed())
+	is.Eventually(channel.IsClosed, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestShutdown_Consumer(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	dialer, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithCapacity(1))
+	is.NoError(err)
+
+	channel, err := client.Channel()
+	is.NoError(err)
+	_, err = channel.QueueDeclare("shutdown.consumer", false, false, false, false, nil)
+	is.NoError(err)
+	is.NoError(channel.Close())
+
+	consumer, err := amqpx.NewConsumer(client, "shutdown.consumer",
+		func(ctx context.Context, event Event, delivery amqp.Delivery) error {
+			return nil
+		},
+	)
+	is.NoError(err)
+
+	consumed := make(chan error, 1)
+	go func() {
+		consumed <- consumer.Consume(context.Background())
+	}()
+	is.Eventually(func() bool {
+		return server.Connections() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	is.NoError(client.Shutdown(ctx))
+	is.True(time.Since(start) < time.Second)
+
+	select {
+	case err = <-consumed:
+		is.NoError(err)
+	case <-time.After(time.Second):
+		is.Fail("consumer not stopped")
+	}
+}
@@ -1,12 +1,14 @@
 package amqpx
 
 import (
-	"fmt"
 	"io"
+	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
-	"github.com/streadway/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // Simple implements the Client interface without a connections pool.
@@ -15,8 +17,12 @@ type Simple struct {
 	mutex      sync.RWMutex
 	dialer     Dialer
 	observer   Observer
-	logger     Logger
-	connection *amqp.Connection
+	logger     StructuredLogger
+	metrics    Metrics
+	connection driverConnection
+	channels   atomic.Int64
+	stop       chan struct{}
+	stopping   bool
 	closed     bool
 }
 
@@ -25,6 +31,8 @@ func newSimple(options *clientOptions) (Client, error) {
 		dialer:   options.dialer,
 		observer: options.observer,
 		logger:   options.logger,
+		metrics:  options.metrics,
+		stop:     make(chan struct{}),
 	}
 
 	err := instance.newConnection()
@@ -36,73 +44,138 @@ func newSimple(options *clientOptions) (Client, error) {
 }
 
 // Channel returns a new Channel from current client unless it's closed.
-func (e *Simple) Channel() (*amqp.Channel, error) {
+func (e *Simple) Channel() (Channel, error) {
+	start := time.Now()
+
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
-	if e.closed {
+	channel, err := e.channel(start)
+	e.metrics.OnChannelOpen(err)
+
+	return channel, err
+}
+
+// channel opens a new Channel on current connection, and renews it if it's closed.
+func (e *Simple) channel(start time.Time) (Channel, error) {
+	if e.closed || e.stopping {
 		return nil, errors.Wrap(ErrClientClosed, ErrMessageCannotOpenChannel)
 	}
 
-	// Try to acquire a channel.
-	channel, err := e.connection.Channel()
+	// Try to acquire a channel, unless a previous renewal of the connection has failed.
+	wait := time.Since(start)
+	channel, err := e.openChannel()
 	if err != nil && err != amqp.ErrClosed {
 		if channel != nil {
 			e.close(channel)
 		}
-		e.logger.Warn("Cannot open a new channel on current connection")
-		return nil, errors.Wrap(err, ErrMessageCannotOpenChannel)
+		e.logger.Warn("Cannot open a new channel on current connection",
+			AddressField(e.connection.LocalAddr()), ErrorField(err))
+		return nil, errors.Wrap(classifyError(err), ErrMessageCannotOpenChannel)
 	}
 
 	// If channel is closed, renew the connection and try again.
 	if err == amqp.ErrClosed {
-		e.logger.Debug("Connection is closed, opening a new one...")
+		e.logger.Debug("Connection is closed, opening a new one...", AddressField(e.address()))
 
 		err = e.newConnection()
 		if err != nil {
 			return nil, err
 		}
+		e.metrics.OnReconnect()
 
+		wait = time.Since(start)
 		channel, err = e.connection.Channel()
 		if err != nil {
 			if channel != nil {
 				e.close(channel)
 			}
-			e.logger.Warn("Cannot open a new channel on current connection")
-			return nil, errors.Wrap(err, ErrMessageCannotOpenChannel)
+			e.logger.Warn("Cannot open a new channel on current connection",
+				AddressField(e.connection.LocalAddr()), ErrorField(err))
+			return nil, errors.Wrap(classifyError(err), ErrMessageCannotOpenChannel)
 		}
 	}
 
-	e.logger.Debug("Opened channel on current connection")
+	e.logger.Debug("Opened channel on current connection", AddressField(e.connection.LocalAddr()))
+	e.metrics.OnConnectionWait(wait)
+	trackChannel(channel, &e.channels)
 
 	return channel, nil
 }
 
+// newConnection closes current connection, if any, and opens a new one.
+// If it fails, the client is left without a connection until the next attempt.
 func (e *Simple) newConnection() error {
 	if e.connection != nil {
 		e.close(e.connection)
+		e.metrics.OnConnectionClose()
+		e.connection = nil
 	}
 
+	start := time.Now()
 	connection, err := e.dialer.dial(0)
+	e.metrics.OnDial(time.Since(start), err)
 	if err != nil {
-		e.logger.Error("Failed to open a new connection")
-		return errors.Wrap(err, ErrMessageCannotOpenConnection)
+		e.logger.Error("Failed to open a new connection", URIField(e.dialer.uri(0)), ErrorField(err))
+		return errors.Wrap(classifyError(err), ErrMessageCannotOpenConnection)
 	}
 
-	e.logger.Debug(fmt.Sprintf("Opened connection %s", connection.LocalAddr()))
+	e.logger.Debug("Opened connection", AddressField(connection.LocalAddr()), URIField(e.dialer.uri(0)))
+	e.metrics.OnConnectionOpen()
 	e.connection = connection
 	return nil
 }
 
-// Close closes the client.
+// Close closes the client. Closing it again is a no-op.
+// It returns a ConnectionCloseError if the connection cannot be closed.
+// Use Shutdown to wait for the borrowed channels to be closed first.
 func (e *Simple) Close() error {
+	return e.closeConnection()
+}
+
+// closeConnection closes the connection once, and returns its error, if any.
+func (e *Simple) closeConnection() error {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
-	e.logger.Debug(fmt.Sprintf("Closing connection %s", e.connection.LocalAddr()))
-	e.close(e.connection)
+
+	if e.closed {
+		return nil
+	}
+
+	e.halt()
+	e.closed = true
+	if e.connection == nil {
+		return nil
+	}
+
+	e.logger.Debug("Closing connection", AddressField(e.connection.LocalAddr()))
+	err := closeOpenConnection(e.connection)
+	e.metrics.OnConnectionClose()
+	if err != nil {
+		e.logger.Error("Failed to close connection", AddressField(e.connection.LocalAddr()), ErrorField(err))
+		e.observer.OnClose(err)
+		return &ConnectionCloseError{Address: addressString(e.connection.LocalAddr()), Err: err}
+	}
+
 	return nil
 }
 
+// openChannel opens a Channel on current connection, or returns amqp.ErrClosed if there is none.
+func (e *Simple) openChannel() (Channel, error) {
+	if e.connection == nil {
+		return nil, amqp.ErrClosed
+	}
+	return e.connection.Channel()
+}
+
+// address returns the local address of current connection, or nil if there is none.
+func (e *Simple) address() net.Addr {
+	if e.connection == nil {
+		return nil
+	}
+	return e.connection.LocalAddr()
+}
+
 // IsClosed returns if the client is closed.
 func (e *Simple) IsClosed() bool {
 	e.mutex.RLock()
@@ -113,11 +186,9 @@ func (e *Simple) IsClosed() bool {
 func (e *Simple) close(connection io.Closer) {
 	err := connection.Close()
 	if err != nil {
-		e.logger.Error("Failed to close connection")
+		e.logger.Error("Failed to close connection", ErrorField(err))
 		e.observer.OnClose(err)
 	}
-
-	e.closed = true
 }
 
 var _ Client = (*Simple)(nil)
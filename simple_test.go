@@ -1,11 +1,15 @@
 package amqpx_test
 
 import (
+	"expvar"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/ulule/amqpx"
+	"github.com/pkg/errors"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
 )
 
 func TestSimpleClient(t *testing.T) {
@@ -26,7 +30,7 @@ func TestSimpleClient_WithInvalidBrokerURI(t *testing.T) {
 	client, err := amqpx.New(dialer, amqpx.WithoutConnectionsPool())
 	is.Error(err)
 	is.Nil(client)
-	is.Contains(err.Error(), amqpx.ErrMessageDialTimeout)
+	is.Contains(err.Error(), amqpx.ErrMessageDialRefused)
 	is.Contains(err.Error(), amqpx.ErrMessageCannotOpenConnection)
 }
 
@@ -66,3 +70,75 @@ func TestSimpleClient_ConcurrentAccess(t *testing.T) {
 	is.NoError(client.Close())
 	wg.Wait()
 }
+
+func TestSimpleClient_CloseDropped(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+
+	client, err := amqpx.New(dialer, amqpx.WithoutConnectionsPool())
+	is.NoError(err)
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	dialer.DropConnections()
+	is.Eventually(channel.IsClosed, 5*time.Second, 20*time.Millisecond)
+
+	// A connection dropped by the broker has not failed to close.
+	is.NoError(client.Close())
+	is.True(client.IsClosed())
+
+	// Closing the client again is a no-op.
+	is.NoError(client.Close())
+}
+
+func TestSimpleClient_WithMetrics_FailedReconnects(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	dialer, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+
+	metrics, err := amqpx.NewExpvarMetrics("amqpx_test_simple_reconnects")
+	is.NoError(err)
+	vars := expvar.Get("amqpx_test_simple_reconnects").(*expvar.Map)
+
+	client, err := amqpx.New(dialer, amqpx.WithoutConnectionsPool(), amqpx.WithMetrics(metrics))
+	is.NoError(err)
+	is.Equal("1", vars.Get(amqpx.MetricConnectionsOpen).String())
+
+	channel, err := client.Channel()
+	is.NoError(err)
+
+	dialer.Refuse(true)
+	dialer.DropConnections()
+	is.Eventually(channel.IsClosed, 5*time.Second, 20*time.Millisecond)
+
+	// Every failed reconnect leaves the client without a connection, which is only counted as closed once.
+	for i := 0; i < 3; i++ {
+		_, err = client.Channel()
+		is.Error(err)
+		is.True(errors.Is(err, amqpx.ErrConnectionRefused))
+		is.Equal("0", vars.Get(amqpx.MetricConnectionsOpen).String())
+	}
+	is.Equal("3", vars.Get(amqpx.MetricDialFailures).String())
+
+	dialer.Refuse(false)
+	channel, err = client.Channel()
+	is.NoError(err)
+	is.NoError(channel.Close())
+	is.Equal("1", vars.Get(amqpx.MetricConnectionsOpen).String())
+	is.Equal("1", vars.Get(amqpx.MetricReconnects).String())
+
+	is.NoError(client.Close())
+	is.Equal("0", vars.Get(amqpx.MetricConnectionsOpen).String())
+}
@@ -0,0 +1,247 @@
+package amqpx
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the connections pools of a Split client.
+const (
+	PoolPublish = "publish"
+	PoolConsume = "consume"
+)
+
+// ConsumeChanneler is implemented by clients with dedicated connections for consumers.
+// A Consumer acquires its channel with ConsumeChannel instead of Channel on such client.
+type ConsumeChanneler interface {
+	// ConsumeChannel returns a new Channel for a consumer.
+	ConsumeChannel() (Channel, error)
+}
+
+// SplitPool defines one of the connections pools of a Split client.
+type SplitPool struct {
+	// Dialer opens the connections of the pool.
+	Dialer Dialer
+	// Options are applied to the pool after the common options, such as WithCapacity.
+	Options []ClientOption
+}
+
+// Split implements the Client interface with two connections pools: one for publishers, and one for consumers.
+// RabbitMQ flow control throttles publishing connections: with their own connections, consumers keep receiving
+// and acknowledging deliveries while publishers are blocked.
+type Split struct {
+	publish *Pool
+	consume *Pool
+}
+
+// NewSplit returns a new Split client with given pools, which have independent capacities and dialers.
+// Given options are applied to both pools, before their own options. Each pool adds its name to the log fields,
+// wraps the errors given to the observer in a PoolError, and uses its own metrics if they implement PoolMetrics.
+// Other metrics are shared by both pools, and the errors they receive are wrapped in a PoolError.
+// Both pools always use a connections pool: WithoutConnectionsPool returns an ErrConnectionsPoolRequired.
+func NewSplit(publish SplitPool, consume SplitPool, options ...ClientOption) (*Split, error) {
+	publisher, err := newSplitPool(PoolPublish, publish, options)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := newSplitPool(PoolConsume, consume, options)
+	if err != nil {
+		thr := publisher.Close()
+		_ = thr
+		return nil, err
+	}
+
+	return &Split{
+		publish: publisher,
+		consume: consumer,
+	}, nil
+}
+
+// newSplitPool returns the connections pool with given name of a Split client.
+func newSplitPool(name string, pool SplitPool, options []ClientOption) (*Pool, error) {
+	opts, err := newClientOptions(pool.Dialer, append(append([]ClientOption{}, options...), pool.Options...))
+	if err != nil {
+		return nil, &PoolError{Pool: name, Err: err}
+	}
+	if !opts.usePool {
+		return nil, &PoolError{Pool: name, Err: errors.Wrap(ErrConnectionsPoolRequired, ErrMessageCannotCreateClient)}
+	}
+
+	opts.logger = &poolLogger{logger: opts.logger, field: PoolField(name)}
+	opts.observer = &poolObserver{observer: opts.observer, pool: name}
+	if metrics, ok := opts.metrics.(PoolMetrics); ok {
+		opts.metrics = metrics.Pool(name)
+	} else {
+		opts.metrics = &poolMetrics{metrics: opts.metrics, pool: name}
+	}
+
+	opts.logger.Debug("Connection pooling is enabled", Field{Key: "capacity", Value: opts.capacity})
+
+	instance, err := newPool(opts)
+	if err != nil {
+		return nil, &PoolError{Pool: name, Err: err}
+	}
+
+	return instance.(*Pool), nil
+}
+
+// Channel returns a new Channel from the publish pool. It's used by a Publisher.
+func (e *Split) Channel() (Channel, error) {
+	return e.PublishChannel()
+}
+
+// PublishChannel returns a new Channel from the publish pool.
+func (e *Split) PublishChannel() (Channel, error) {
+	return e.publish.Channel()
+}
+
+// ConsumeChannel implements ConsumeChanneler interface: it returns a new Channel from the consume pool.
+func (e *Split) ConsumeChannel() (Channel, error) {
+	return e.consume.Channel()
+}
+
+// PublishPool returns the publish pool, to resize it for example.
+func (e *Split) PublishPool() *Pool {
+	return e.publish
+}
+
+// ConsumePool returns the consume pool, to resize it for example.
+func (e *Split) ConsumePool() *Pool {
+	return e.consume
+}
+
+// Close closes both pools, and returns the errors of the connections which cannot be closed.
+func (e *Split) Close() error {
+	list := errorList{}
+	list.add(wrapPoolError(PoolPublish, e.publish.Close()))
+	list.add(wrapPoolError(PoolConsume, e.consume.Close()))
+	return list.err()
+}
+
+// Shutdown gracefully closes both pools at the same time, until given context is done.
+func (e *Split) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- e.consume.Shutdown(ctx)
+	}()
+
+	list := errorList{}
+	list.add(wrapPoolError(PoolPublish, e.publish.Shutdown(ctx)))
+	list.add(wrapPoolError(PoolConsume, <-done))
+	return list.err()
+}
+
+// ShuttingDown implements ShutdownNotifier interface: it notifies the shutdown of the consume pool.
+func (e *Split) ShuttingDown() <-chan struct{} {
+	return e.consume.ShuttingDown()
+}
+
+// IsClosed returns if both pools are closed.
+func (e *Split) IsClosed() bool {
+	return e.publish.IsClosed() && e.consume.IsClosed()
+}
+
+// wrapPoolError returns given error in a PoolError with given pool name, unless it's nil.
+func wrapPoolError(pool string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PoolError{Pool: pool, Err: err}
+}
+
+// poolLogger is a StructuredLogger which adds the name of a connections pool to the fields of each message.
+type poolLogger struct {
+	logger StructuredLogger
+	field  Field
+}
+
+// Debug implements StructuredLogger interface.
+func (e *poolLogger) Debug(message string, fields ...Field) {
+	e.logger.Debug(message, e.fields(fields)...)
+}
+
+// Info implements StructuredLogger interface.
+func (e *poolLogger) Info(message string, fields ...Field) {
+	e.logger.Info(message, e.fields(fields)...)
+}
+
+// Warn implements StructuredLogger interface.
+func (e *poolLogger) Warn(message string, fields ...Field) {
+	e.logger.Warn(message, e.fields(fields)...)
+}
+
+// Error implements StructuredLogger interface.
+func (e *poolLogger) Error(message string, fields ...Field) {
+	e.logger.Error(message, e.fields(fields)...)
+}
+
+// fields returns a copy of given fields with the name of the connections pool, so the caller's slice is never
+// written to.
+func (e *poolLogger) fields(fields []Field) []Field {
+	return append(append(make([]Field, 0, len(fields)+1), fields...), e.field)
+}
+
+// poolObserver is an Observer which wraps the errors of a connections pool in a PoolError.
+type poolObserver struct {
+	observer Observer
+	pool     string
+}
+
+// OnError implements Observer interface.
+func (e *poolObserver) OnError(err error) {
+	e.observer.OnError(&PoolError{Pool: e.pool, Err: err})
+}
+
+// OnClose implements Observer interface.
+func (e *poolObserver) OnClose(err error) {
+	e.observer.OnClose(&PoolError{Pool: e.pool, Err: err})
+}
+
+// poolMetrics is a Metrics shared by the connections pools of a Split client, which wraps the errors of a
+// connections pool in a PoolError.
+type poolMetrics struct {
+	metrics Metrics
+	pool    string
+}
+
+// OnDial implements Metrics interface.
+func (e *poolMetrics) OnDial(duration time.Duration, err error) {
+	e.metrics.OnDial(duration, wrapPoolError(e.pool, err))
+}
+
+// OnConnectionOpen implements Metrics interface.
+func (e *poolMetrics) OnConnectionOpen() {
+	e.metrics.OnConnectionOpen()
+}
+
+// OnConnectionClose implements Metrics interface.
+func (e *poolMetrics) OnConnectionClose() {
+	e.metrics.OnConnectionClose()
+}
+
+// OnReconnect implements Metrics interface.
+func (e *poolMetrics) OnReconnect() {
+	e.metrics.OnReconnect()
+}
+
+// OnConnectionWait implements Metrics interface.
+func (e *poolMetrics) OnConnectionWait(duration time.Duration) {
+	e.metrics.OnConnectionWait(duration)
+}
+
+// OnChannelOpen implements Metrics interface.
+func (e *poolMetrics) OnChannelOpen(err error) {
+	e.metrics.OnChannelOpen(wrapPoolError(e.pool, err))
+}
+
+var (
+	_ Client           = (*Split)(nil)
+	_ ConsumeChanneler = (*Split)(nil)
+	_ ShutdownNotifier = (*Split)(nil)
+	_ StructuredLogger = (*poolLogger)(nil)
+	_ Observer         = (*poolObserver)(nil)
+	_ Metrics          = (*poolMetrics)(nil)
+)
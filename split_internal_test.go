@@ -0,0 +1,32 @@
+package amqpx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fieldsLogger is a StructuredLogger which records the fields of the last message.
+type fieldsLogger struct {
+	noopLogger
+	fields []Field
+}
+
+func (e *fieldsLogger) Debug(message string, fields ...Field) {
+	e.fields = fields
+}
+
+func TestPoolLogger_Fields(t *testing.T) {
+	is := require.New(t)
+
+	recorder := &fieldsLogger{}
+	logger := &poolLogger{logger: recorder, field: PoolField(PoolConsume)}
+
+	// The caller's slice has spare capacity: it must not be written to.
+	fields := make([]Field, 1, 2)
+	fields[0] = SlotField(1)
+	logger.Debug("Opened channel", fields...)
+
+	is.Equal([]Field{SlotField(1), PoolField(PoolConsume)}, recorder.fields)
+	is.Equal(Field{}, fields[:2][1])
+}
@@ -0,0 +1,210 @@
+package amqpx_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+	"github.com/ulule/amqpx/v3/amqpxtest"
+)
+
+func TestSplit(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	publish, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	consume, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+
+	metrics, err := amqpx.NewExpvarMetrics("amqpx_test_split")
+	is.NoError(err)
+
+	buffer := &SafeBuffer{}
+	client, err := amqpx.NewSplit(
+		amqpx.SplitPool{Dialer: publish, Options: []amqpx.ClientOption{amqpx.WithCapacity(1)}},
+		amqpx.SplitPool{Dialer: consume, Options: []amqpx.ClientOption{amqpx.WithCapacity(2)}},
+		amqpx.WithMetrics(metrics),
+		amqpx.WithStructuredLogger(amqpx.NewJSONLogger(buffer, amqpx.LoggerLevelDebug)),
+	)
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	is.Equal(1, publish.Connections())
+	is.Equal(2, consume.Connections())
+	is.Equal(1, client.PublishPool().Length())
+	is.Equal(2, client.ConsumePool().Length())
+
+	channel, err := client.PublishChannel()
+	is.NoError(err)
+	_, err = channel.QueueDeclare("split", false, false, false, false, nil)
+	is.NoError(err)
+	is.NoError(channel.Close())
+
+	publisher, err := amqpx.NewPublisher[Event](client, "", "split")
+	is.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan Event, 1)
+	consumer, err := amqpx.NewConsumer(client, "split",
+		func(ctx context.Context, event Event, delivery amqp.Delivery) error {
+			received <- event
+			return nil
+		},
+	)
+	is.NoError(err)
+
+	go func() {
+		thr := consumer.Consume(ctx)
+		_ = thr
+	}()
+
+	// The message is published on the publish pool, and consumed on the consume pool.
+	is.NoError(publisher.Publish(ctx, Event{Message: "hello"}))
+
+	select {
+	case event := <-received:
+		is.Equal("hello", event.Message)
+	case <-ctx.Done():
+		is.NoError(ctx.Err())
+	}
+
+	vars := expvar.Get("amqpx_test_split").(*expvar.Map)
+	is.Equal("1", vars.Get("publish_"+amqpx.MetricConnectionsOpen).String())
+	is.Equal("2", vars.Get("consume_"+amqpx.MetricConnectionsOpen).String())
+	is.Equal("1", vars.Get("consume_"+amqpx.MetricChannelsOpened).String())
+
+	pools := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		entry := map[string]interface{}{}
+		is.NoError(json.Unmarshal([]byte(line), &entry))
+		if entry["message"] == "Opened connection" {
+			pools[entry[amqpx.FieldPool].(string)] = true
+		}
+	}
+	is.True(pools[amqpx.PoolPublish])
+	is.True(pools[amqpx.PoolConsume])
+}
+
+func TestSplit_Failure(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	consume, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	consume.Refuse(true)
+
+	client, err := amqpx.NewSplit(
+		amqpx.SplitPool{Dialer: simple},
+		amqpx.SplitPool{Dialer: consume},
+		amqpx.WithCapacity(2),
+	)
+	is.Nil(client)
+	is.Error(err)
+
+	failure := &amqpx.PoolError{}
+	is.True(errors.As(err, &failure))
+	is.Equal(amqpx.PoolConsume, failure.Pool)
+	is.Contains(err.Error(), "consume pool: ")
+
+	// The connections of the publish pool are closed.
+	is.Eventually(func() bool {
+		return server.Connections() == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestSplit_WithoutConnectionsPool(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	client, err := amqpx.NewSplit(
+		amqpx.SplitPool{Dialer: simple, Options: []amqpx.ClientOption{amqpx.WithoutConnectionsPool()}},
+		amqpx.SplitPool{Dialer: simple},
+		amqpx.WithCapacity(1),
+	)
+	is.Nil(client)
+	is.True(errors.Is(err, amqpx.ErrConnectionsPoolRequired))
+
+	failure := &amqpx.PoolError{}
+	is.True(errors.As(err, &failure))
+	is.Equal(amqpx.PoolPublish, failure.Pool)
+	is.Equal(0, server.Connections())
+}
+
+// dialErrorsMetrics is a Metrics which records the dial errors.
+type dialErrorsMetrics struct {
+	amqpx.Metrics
+	mutex  sync.Mutex
+	errors []error
+}
+
+func (e *dialErrorsMetrics) OnDial(duration time.Duration, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if err != nil {
+		e.errors = append(e.errors, err)
+	}
+}
+
+func (e *dialErrorsMetrics) Errors() []error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]error{}, e.errors...)
+}
+
+func TestSplit_WithMetrics(t *testing.T) {
+	is := NewRunner(t)
+
+	server := NewServer(t, amqpxtest.NewBroker())
+	simple, err := amqpx.SimpleDialer(server.URI())
+	is.NoError(err)
+
+	consume, err := amqpx.NewFaultDialer(simple)
+	is.NoError(err)
+	consume.RefuseNext(1)
+
+	base, err := amqpx.NewExpvarMetrics("amqpx_test_split_shared")
+	is.NoError(err)
+	metrics := &dialErrorsMetrics{Metrics: base}
+
+	client, err := amqpx.NewSplit(
+		amqpx.SplitPool{Dialer: simple},
+		amqpx.SplitPool{Dialer: consume, Options: []amqpx.ClientOption{amqpx.WithMinConnections(1)}},
+		amqpx.WithCapacity(2),
+		amqpx.WithStartupParallelism(1),
+		amqpx.WithMetrics(metrics),
+	)
+	is.NoError(err)
+	defer func() {
+		is.NoError(client.Close())
+	}()
+
+	// Metrics which don't implement PoolMetrics receive the errors of each pool in a PoolError.
+	errs := metrics.Errors()
+	is.Equal(1, len(errs))
+	failure := &amqpx.PoolError{}
+	is.True(errors.As(errs[0], &failure))
+	is.Equal(amqpx.PoolConsume, failure.Pool)
+	is.Contains(errs[0].Error(), "consume pool: ")
+}
@@ -0,0 +1,94 @@
+package amqpx
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Trace context headers, as defined by W3C Trace Context.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// TraceContext is a W3C trace context.
+type TraceContext struct {
+	TraceParent string
+	TraceState  string
+}
+
+// IsZero returns if the trace context is empty.
+func (e TraceContext) IsZero() bool {
+	return e.TraceParent == ""
+}
+
+// Tracer propagates a trace context between a context.Context and amqp headers.
+// It allows to bind amqpx with any tracing SDK.
+type Tracer interface {
+	// Inject returns the trace context of given context.
+	Inject(ctx context.Context) TraceContext
+
+	// Extract returns a copy of given context with given trace context.
+	Extract(ctx context.Context, trace TraceContext) context.Context
+}
+
+// InjectTrace writes the trace context of given context in message headers.
+func InjectTrace(ctx context.Context, tracer Tracer, message *amqp.Publishing) {
+	trace := tracer.Inject(ctx)
+	if trace.IsZero() {
+		return
+	}
+
+	if message.Headers == nil {
+		message.Headers = amqp.Table{}
+	}
+
+	message.Headers[TraceParentHeader] = trace.TraceParent
+	if trace.TraceState != "" {
+		message.Headers[TraceStateHeader] = trace.TraceState
+	}
+}
+
+// ExtractTrace returns a copy of given context with the trace context found in delivery headers.
+func ExtractTrace(ctx context.Context, tracer Tracer, delivery amqp.Delivery) context.Context {
+	trace := TraceContext{
+		TraceParent: headerString(delivery.Headers, TraceParentHeader),
+		TraceState:  headerString(delivery.Headers, TraceStateHeader),
+	}
+	if trace.IsZero() {
+		return ctx
+	}
+
+	return tracer.Extract(ctx, trace)
+}
+
+// TraceInterceptor returns a PublishInterceptor that injects the trace context in message headers.
+func TraceInterceptor(tracer Tracer) PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+			InjectTrace(ctx, tracer, message)
+			return next(ctx, exchange, key, message)
+		}
+	}
+}
+
+// TraceMiddleware returns a Middleware that extracts the trace context from delivery headers.
+func TraceMiddleware(tracer Tracer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, delivery amqp.Delivery) error {
+			return next(ExtractTrace(ctx, tracer, delivery), delivery)
+		}
+	}
+}
+
+func headerString(headers amqp.Table, key string) string {
+	switch value := headers[key].(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,10 @@
+package amqpx
+
+import (
+	"context"
+)
+
+type noopTracer struct{}
+
+func (noopTracer) Inject(ctx context.Context) TraceContext                         { return TraceContext{} }
+func (noopTracer) Extract(ctx context.Context, trace TraceContext) context.Context { return ctx }
@@ -0,0 +1,71 @@
+package amqpx
+
+import (
+	"context"
+	"sync"
+)
+
+type traceRecorderKey struct{}
+
+// TraceRecorder is an in-memory Tracer which records every propagated trace context.
+// It's intended to be used in tests.
+type TraceRecorder struct {
+	mutex     sync.Mutex
+	injected  []TraceContext
+	extracted []TraceContext
+}
+
+// NewTraceRecorder returns a new TraceRecorder instance.
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{}
+}
+
+// Start returns a copy of given context with given trace context.
+func (e *TraceRecorder) Start(ctx context.Context, trace TraceContext) context.Context {
+	return context.WithValue(ctx, traceRecorderKey{}, trace)
+}
+
+// Trace returns the trace context of given context, if any.
+func (e *TraceRecorder) Trace(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(traceRecorderKey{}).(TraceContext)
+	return trace, ok
+}
+
+// Inject implements Tracer interface.
+func (e *TraceRecorder) Inject(ctx context.Context) TraceContext {
+	trace, ok := e.Trace(ctx)
+	if !ok {
+		return TraceContext{}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.injected = append(e.injected, trace)
+
+	return trace
+}
+
+// Extract implements Tracer interface.
+func (e *TraceRecorder) Extract(ctx context.Context, trace TraceContext) context.Context {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.extracted = append(e.extracted, trace)
+
+	return e.Start(ctx, trace)
+}
+
+// Injected returns every injected trace context.
+func (e *TraceRecorder) Injected() []TraceContext {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]TraceContext{}, e.injected...)
+}
+
+// Extracted returns every extracted trace context.
+func (e *TraceRecorder) Extracted() []TraceContext {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]TraceContext{}, e.extracted...)
+}
+
+var _ Tracer = (*TraceRecorder)(nil)
@@ -0,0 +1,65 @@
+package amqpx_test
+
+import (
+	"context"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/ulule/amqpx/v3"
+)
+
+func TestTracer(t *testing.T) {
+	is := NewRunner(t)
+
+	recorder := amqpx.NewTraceRecorder()
+	trace := amqpx.TraceContext{
+		TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		TraceState:  "congo=t61rcWkgMzE",
+	}
+
+	message := &amqp.Publishing{}
+	amqpx.InjectTrace(context.Background(), recorder, message)
+	is.Nil(message.Headers)
+
+	amqpx.InjectTrace(recorder.Start(context.Background(), trace), recorder, message)
+	is.Equal(trace.TraceParent, message.Headers[amqpx.TraceParentHeader])
+	is.Equal(trace.TraceState, message.Headers[amqpx.TraceStateHeader])
+	is.Equal([]amqpx.TraceContext{trace}, recorder.Injected())
+
+	ctx := amqpx.ExtractTrace(context.Background(), recorder, amqp.Delivery{})
+	_, ok := recorder.Trace(ctx)
+	is.False(ok)
+
+	ctx = amqpx.ExtractTrace(context.Background(), recorder, amqp.Delivery{Headers: message.Headers})
+	extracted, ok := recorder.Trace(ctx)
+	is.True(ok)
+	is.Equal(trace, extracted)
+	is.Equal([]amqpx.TraceContext{trace}, recorder.Extracted())
+}
+
+func TestTracer_Propagation(t *testing.T) {
+	is := NewRunner(t)
+
+	recorder := amqpx.NewTraceRecorder()
+	trace := amqpx.TraceContext{
+		TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+
+	var headers amqp.Table
+	publish := amqpx.ChainPublish(func(ctx context.Context, exchange string, key string, message *amqp.Publishing) error {
+		headers = message.Headers
+		return nil
+	}, amqpx.TraceInterceptor(recorder))
+
+	is.NoError(publish(recorder.Start(context.Background(), trace), warpExchange, "random.tracer", &amqp.Publishing{}))
+
+	handler := amqpx.Chain(func(ctx context.Context, delivery amqp.Delivery) error {
+		extracted, ok := recorder.Trace(ctx)
+		is.True(ok)
+		is.Equal(trace, extracted)
+		return nil
+	}, amqpx.TraceMiddleware(recorder))
+
+	is.NoError(handler(context.Background(), amqp.Delivery{Headers: headers}))
+}